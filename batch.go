@@ -0,0 +1,167 @@
+package dbobj
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// BatchWriter accumulates object inserts, writing them with InsertMany
+// every maxN objects or interval, whichever comes first.
+// It is safe for concurrent use
+type BatchWriter struct {
+	du    *DBU
+	table string
+	maxN  int
+
+	mu      sync.Mutex
+	pending []batchRow
+	err     error // error of the last interval flush
+	closed  bool
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// batchRow is a pending insert
+type batchRow struct {
+	query string
+	args  []interface{}
+}
+
+// NewBatchWriter returns a BatchWriter for objects of o's table. A maxN or interval
+// of zero disables flushing by count or by time, respectively
+func NewBatchWriter(du *DBU, o DBObject, maxN int, interval time.Duration) *BatchWriter {
+	w := &BatchWriter{
+		du:    du,
+		table: tableName(o),
+		maxN:  maxN,
+		done:  make(chan struct{}),
+	}
+	if interval > 0 {
+		w.wg.Add(1)
+		go w.tick(interval)
+	}
+	return w
+}
+
+// tick flushes the pending objects every interval until the writer is closed
+func (w *BatchWriter) tick(interval time.Duration) {
+	defer w.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			if err := w.flush(); err != nil {
+				w.err = err
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+// Add queues the object for insertion, flushing if the batch is full.
+// Keys assigned by the database are not set on the object.
+// It returns the error of a failed interval flush, if any since the last call
+func (w *BatchWriter) Add(o DBObject) error {
+	if err := validate(o); err != nil {
+		return err
+	}
+	if table := tableName(o); table != w.table {
+		return errors.Errorf("mixed tables in BatchWriter: %s and %s", w.table, table)
+	}
+	query, args := insertQuery(o, w.du.dialect)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrClosed
+	}
+	if err := w.takeErr(); err != nil {
+		return err
+	}
+	w.pending = append(w.pending, batchRow{query: query, args: args})
+	if w.maxN > 0 && len(w.pending) >= w.maxN {
+		return w.flush()
+	}
+	return nil
+}
+
+// Flush inserts the pending objects
+func (w *BatchWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.takeErr(); err != nil {
+		return err
+	}
+	return w.flush()
+}
+
+// Close stops interval flushing and inserts the pending objects, returning
+// the error of a failed interval flush, if any, along with those of its own.
+// It returns ErrClosed if it was already closed
+func (w *BatchWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrClosed
+	}
+	w.closed = true
+	w.mu.Unlock()
+	close(w.done)
+	w.wg.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.takeErr()
+	// each failed run is dropped, so the rest are always attempted
+	for len(w.pending) > 0 {
+		if e := w.flush(); e != nil {
+			err = combine(err, e)
+		}
+	}
+	return err
+}
+
+// combine returns the error of err followed by next
+func combine(err, next error) error {
+	if err == nil {
+		return next
+	}
+	return errors.Wrapf(next, "%v; then", err)
+}
+
+// takeErr returns and clears the error of the last interval flush
+func (w *BatchWriter) takeErr() error {
+	err := w.err
+	w.err = nil
+	return err
+}
+
+// flush inserts the pending objects, a transaction for each run of them sharing
+// an insert statement. The objects of a run that fails to insert are dropped,
+// and those of the runs following it are kept pending
+func (w *BatchWriter) flush() error {
+	rows := w.pending
+	defer func() { w.pending = rows }()
+	for len(rows) > 0 {
+		n := 1
+		for n < len(rows) && rows[n].query == rows[0].query {
+			n++
+		}
+		args := make([][]interface{}, n)
+		for i := range args {
+			args[i] = rows[i].args
+		}
+		err := w.du.InsertMany(rows[0].query, args...)
+		rows = rows[n:]
+		if err != nil {
+			return errors.Wrapf(err, "batch insert of %d into %s", n, w.table)
+		}
+	}
+	return nil
+}
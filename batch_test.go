@@ -0,0 +1,127 @@
+package dbobj
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatchWriter(t *testing.T) {
+	db := structDBU(t)
+	before := countStructs(t, db)
+	w := NewBatchWriter(db, &testStruct{}, 10, 50*time.Millisecond)
+	for i := 0; i < 3; i++ {
+		if err := w.Add(&testStruct{Name: fmt.Sprintf("batch %d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := countStructs(t, db); n != before {
+		t.Fatalf("expected no inserts below the threshold, got %d new", n-before)
+	}
+	// poll the writer rather than the table, which would contend for the connection
+	deadline := time.Now().Add(time.Second)
+	for {
+		w.mu.Lock()
+		pending, err := len(w.pending), w.err
+		w.mu.Unlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pending == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected an interval flush, %d pending", pending)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := countStructs(t, db); n != before+3 {
+		t.Fatalf("expected an interval flush of 3, got %d new", n-before)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != ErrClosed {
+		t.Errorf("expected ErrClosed on second close, got %v", err)
+	}
+	if err := w.Add(&testStruct{Name: "late"}); err != ErrClosed {
+		t.Errorf("expected ErrClosed after close, got %v", err)
+	}
+}
+
+func TestBatchWriterCount(t *testing.T) {
+	db := structDBU(t)
+	before := countStructs(t, db)
+	w := NewBatchWriter(db, &testStruct{}, 4, 0)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := w.Add(&testStruct{Name: fmt.Sprintf("batch %d", i)}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := w.Add(&shardStruct{}); err == nil {
+		t.Error("expected error adding another table")
+	}
+	if n := countStructs(t, db) - before; n != 8 {
+		t.Errorf("expected 2 full batches of 4, got %d new", n)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := countStructs(t, db) - before; n != 10 {
+		t.Errorf("expected close to flush the rest, got %d new", n)
+	}
+}
+
+// batchRows returns the pending inserts of a good object, a failing one, then another good one
+func batchRows(t *testing.T) []batchRow {
+	t.Helper()
+	first, args := insertQuery(&testStruct{Name: "first"}, SQLite)
+	second, more := insertQuery(&testStruct{Name: "second"}, SQLite)
+	return []batchRow{
+		{query: first, args: args},
+		{query: "insert into structs (bogus) values (?)", args: []interface{}{1}},
+		{query: second, args: more},
+	}
+}
+
+func TestBatchWriterFailedRun(t *testing.T) {
+	db := structDBU(t)
+	before := countStructs(t, db)
+	w := NewBatchWriter(db, &testStruct{}, 0, 0)
+	w.pending = batchRows(t)
+	if err := w.Flush(); err == nil {
+		t.Fatal("expected the failed run to be reported")
+	}
+	if n := countStructs(t, db) - before; n != 1 || len(w.pending) != 1 {
+		t.Fatalf("expected 1 insert and 1 pending, got %d and %d", n, len(w.pending))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := countStructs(t, db) - before; n != 2 {
+		t.Errorf("expected the run after the failure to be inserted, got %d new", n)
+	}
+}
+
+func TestBatchWriterCloseAfterError(t *testing.T) {
+	db := structDBU(t)
+	before := countStructs(t, db)
+	w := NewBatchWriter(db, &testStruct{}, 0, 0)
+	w.err = fmt.Errorf("interval flush failed")
+	w.pending = batchRows(t)
+	err := w.Close()
+	if err == nil || !strings.Contains(err.Error(), "interval flush failed") || !strings.Contains(err.Error(), "bogus") {
+		t.Errorf("expected both errors, got %v", err)
+	}
+	if n := countStructs(t, db) - before; n != 2 || len(w.pending) != 0 {
+		t.Errorf("expected close to insert the 2 good rows, got %d new and %d pending", n, len(w.pending))
+	}
+}
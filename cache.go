@@ -0,0 +1,101 @@
+package dbobj
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// cacheEntry holds the column values of a cached object
+type cacheEntry struct {
+	values  []interface{}
+	expires time.Time
+}
+
+// EnableCache memoizes FindByID results for the given duration.
+// A zero duration disables the cache.
+func (du *DBU) EnableCache(ttl time.Duration) {
+	du.mu.Lock()
+	du.cacheTTL = ttl
+	du.cache = make(map[string]cacheEntry)
+	du.mu.Unlock()
+}
+
+// InvalidateCache removes the object from the cache
+func (du *DBU) InvalidateCache(o DBObject) {
+	du.invalidate(o, keyValue(o))
+}
+
+func (du *DBU) invalidate(o DBObject, id interface{}) {
+	du.mu.Lock()
+	if du.cache != nil {
+		delete(du.cache, cacheKey(o, id))
+	}
+	du.mu.Unlock()
+}
+
+// invalidateTable removes all cached objects of the same table
+func (du *DBU) invalidateTable(o DBObject) {
+	prefix := tableName(o) + ":"
+	du.mu.Lock()
+	for k := range du.cache {
+		if strings.HasPrefix(k, prefix) {
+			delete(du.cache, k)
+		}
+	}
+	du.mu.Unlock()
+}
+
+func cacheKey(o DBObject, id interface{}) string {
+	return fmt.Sprintf("%s:%v", tableName(o), id)
+}
+
+// cacheLoad populates the object from the cache, reporting whether it was found
+func (du *DBU) cacheLoad(o DBObject, id interface{}) bool {
+	du.mu.RLock()
+	entry, ok := du.cache[cacheKey(o, id)]
+	du.mu.RUnlock()
+	if !ok || time.Now().After(entry.expires) {
+		return false
+	}
+	members := o.MemberPointers()
+	if len(members) != len(entry.values) {
+		return false
+	}
+	for i, ptr := range members {
+		elem := reflect.ValueOf(ptr).Elem()
+		if entry.values[i] == nil {
+			elem.Set(reflect.Zero(elem.Type()))
+			continue
+		}
+		elem.Set(reflect.ValueOf(copyValue(entry.values[i])))
+	}
+	return true
+}
+
+// cacheStore saves the current values of the object in the cache
+func (du *DBU) cacheStore(o DBObject, id interface{}) {
+	du.mu.Lock()
+	defer du.mu.Unlock()
+	if du.cacheTTL == 0 || du.cache == nil {
+		return
+	}
+	members := o.MemberPointers()
+	values := make([]interface{}, len(members))
+	for i, ptr := range members {
+		values[i] = copyValue(reflect.ValueOf(ptr).Elem().Interface())
+	}
+	du.cache[cacheKey(o, id)] = cacheEntry{
+		values:  values,
+		expires: time.Now().Add(du.cacheTTL),
+	}
+}
+
+// copyValue returns a copy of byte slices so cached data isn't shared
+func copyValue(v interface{}) interface{} {
+	if b, ok := v.([]byte); ok && b != nil {
+		return append([]byte(nil), b...)
+	}
+	return v
+}
@@ -0,0 +1,149 @@
+package dbobj
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// queryCounter records the queries logged
+type queryCounter struct {
+	queries []string
+}
+
+func (q *queryCounter) Debugf(msg string, args ...interface{}) {
+	if len(args) > 0 {
+		if query, ok := args[0].(string); ok {
+			q.queries = append(q.queries, query)
+		}
+	}
+}
+
+// count returns the number of queries logged starting with prefix
+func (q *queryCounter) count(prefix string) int {
+	n := 0
+	for _, query := range q.queries {
+		if strings.HasPrefix(query, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestCache(t *testing.T) {
+	db := structDBU(t)
+	counter := &queryCounter{}
+	db.SetLogger(counter)
+	db.EnableCache(time.Minute)
+
+	s := testStruct{}
+	if err := db.FindByID(&s, 1); err != nil {
+		t.Fatal(err)
+	}
+	u := testStruct{}
+	if err := db.FindByID(&u, 1); err != nil {
+		t.Fatal(err)
+	}
+	if counter.count("select") != 1 {
+		t.Fatalf("expected 1 query, got %d", counter.count("select"))
+	}
+	if u.Name != s.Name || u.ID != 1 {
+		t.Fatalf("cached record mismatch: %+v vs %+v", u, s)
+	}
+
+	u.Name = "cache buster"
+	if err := db.Save(&u); err != nil {
+		t.Fatal(err)
+	}
+	v := testStruct{}
+	if err := db.FindByID(&v, 1); err != nil {
+		t.Fatal(err)
+	}
+	if counter.count("select") != 2 {
+		t.Fatalf("expected save to invalidate cache, got %d queries", counter.count("select"))
+	}
+	if v.Name != "cache buster" {
+		t.Errorf("expected updated name, got %q", v.Name)
+	}
+
+	db.InvalidateCache(&v)
+	if err := db.FindByID(&v, 1); err != nil {
+		t.Fatal(err)
+	}
+	if counter.count("select") != 3 {
+		t.Fatalf("expected invalidation to force query, got %d queries", counter.count("select"))
+	}
+}
+
+func TestCacheExpires(t *testing.T) {
+	db := structDBU(t)
+	counter := &queryCounter{}
+	db.SetLogger(counter)
+	db.EnableCache(time.Millisecond)
+	s := testStruct{}
+	if err := db.FindByID(&s, 1); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if err := db.FindByID(&s, 1); err != nil {
+		t.Fatal(err)
+	}
+	if counter.count("select") != 2 {
+		t.Fatalf("expected expired entry to be reloaded, got %d queries", counter.count("select"))
+	}
+}
+
+func TestCacheStringKey(t *testing.T) {
+	db := structDBU(t)
+	if _, _, err := db.Exec("create table codes (code text primary key, name text)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Add(&codeStruct{Code: "abc", Name: "first"}); err != nil {
+		t.Fatal(err)
+	}
+	db.EnableCache(time.Minute)
+	find := func(want string) {
+		t.Helper()
+		c := codeStruct{}
+		if err := db.FindByID(&c, "abc"); err != nil {
+			t.Fatal(err)
+		}
+		if c.Name != want {
+			t.Errorf("expected name %q, got %q", want, c.Name)
+		}
+	}
+	find("first")
+	if err := db.Save(&codeStruct{Code: "abc", Name: "saved"}); err != nil {
+		t.Fatal(err)
+	}
+	find("saved")
+	if err := db.Replace(&codeStruct{Code: "abc", Name: "replaced"}); err != nil {
+		t.Fatal(err)
+	}
+	find("replaced")
+	if err := db.Delete(&codeStruct{Code: "abc"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.FindByID(&codeStruct{}, "abc"); err != ErrNotFound {
+		t.Errorf("expected deleted code to be gone, got %v", err)
+	}
+}
+
+func TestCacheReplace(t *testing.T) {
+	db := structDBU(t)
+	db.EnableCache(time.Minute)
+	s := testStruct{}
+	if err := db.FindByID(&s, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Replace(&testStruct{ID: 1, Name: "replaced"}); err != nil {
+		t.Fatal(err)
+	}
+	u := testStruct{}
+	if err := db.FindByID(&u, 1); err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != "replaced" {
+		t.Errorf("expected replace to invalidate the cache, got %q", u.Name)
+	}
+}
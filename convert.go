@@ -0,0 +1,352 @@
+package dbobj
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Value is an alias of driver.Value, allowing generated
+// code to implement driver.Valuer without importing driver
+type Value = driver.Value
+
+// ScanInt converts a scanned column value to an integer,
+// for generated enum types. A NULL column is zero.
+func ScanInt(src interface{}) (int64, error) {
+	switch v := src.(type) {
+	case nil:
+		return 0, nil
+	case int64:
+		return v, nil
+	case float64:
+		return int64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case []byte:
+		return parseInt(string(v))
+	case string:
+		return parseInt(v)
+	}
+	return 0, fmt.Errorf("cannot scan %T into an integer", src)
+}
+
+func parseInt(s string) (int64, error) {
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer %q: %v", s, err)
+	}
+	return i, nil
+}
+
+// Blob stores a string in a blob column as bytes rather than text.
+// Generated code converts string fields tagged blob:"true", e.g., (*Blob)(&o.Data),
+// though []byte members are preferred for binary data
+type Blob string
+
+// Value satisfies the driver.Valuer interface
+func (b Blob) Value() (driver.Value, error) {
+	return []byte(b), nil
+}
+
+// Scan satisfies the sql.Scanner interface
+func (b *Blob) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*b = ""
+	case []byte:
+		*b = Blob(v)
+	case string:
+		*b = Blob(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Blob", src)
+	}
+	return nil
+}
+
+// Delimited stores a []string in a text column, joined by Sep.
+// Generated code wraps fields tagged split, e.g., &Delimited{Strings: &o.Tags, Sep: ","}
+type Delimited struct {
+	Strings *[]string
+	Sep     string
+}
+
+// Value satisfies the driver.Valuer interface
+func (d Delimited) Value() (driver.Value, error) {
+	if d.Strings == nil {
+		return "", nil
+	}
+	return strings.Join(*d.Strings, d.Sep), nil
+}
+
+// Scan satisfies the sql.Scanner interface.
+// An empty or NULL column is an empty slice
+func (d *Delimited) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("cannot scan %T into Delimited", src)
+	}
+	if s == "" {
+		*d.Strings = []string{}
+		return nil
+	}
+	*d.Strings = strings.Split(s, d.Sep)
+	return nil
+}
+
+// StringsEqual reports whether the slices hold the same strings, for generated Equal methods
+func StringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// omitted is the value of an empty field tagged omitempty, which inserts leave out.
+// Elsewhere it is bound as NULL
+type omitted struct{}
+
+// Value satisfies the driver.Valuer interface
+func (omitted) Value() (driver.Value, error) {
+	return nil, nil
+}
+
+// OmitEmpty marks the zero value of v as omitted, so generated inserts leave out
+// fields tagged omitempty and the column's default applies.
+// A nil value, by contrast, is inserted as NULL
+func OmitEmpty(v interface{}) interface{} {
+	if v == nil || reflect.ValueOf(v).IsZero() {
+		return omitted{}
+	}
+	return v
+}
+
+// UnixTime stores a time.Time as integer epoch seconds.
+// Generated code converts tagged fields, e.g., (*UnixTime)(&o.Modified)
+type UnixTime time.Time
+
+// Value satisfies the driver.Valuer interface
+func (u UnixTime) Value() (driver.Value, error) {
+	t := time.Time(u)
+	if t.IsZero() {
+		return nil, nil
+	}
+	return t.Unix(), nil
+}
+
+// Scan satisfies the sql.Scanner interface
+func (u *UnixTime) Scan(src interface{}) error {
+	var secs int64
+	switch v := src.(type) {
+	case nil:
+		*u = UnixTime(time.Time{})
+		return nil
+	case int64:
+		secs = v
+	case float64:
+		secs = int64(v)
+	case []byte:
+		return u.parse(string(v))
+	case string:
+		return u.parse(v)
+	default:
+		return fmt.Errorf("cannot scan %T into UnixTime", src)
+	}
+	*u = UnixTime(time.Unix(secs, 0))
+	return nil
+}
+
+func (u *UnixTime) parse(s string) error {
+	secs, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid epoch time %q: %v", s, err)
+	}
+	*u = UnixTime(time.Unix(secs, 0))
+	return nil
+}
+
+// NullableTime scans a time.Time, tolerating NULL (the zero time)
+// and datetimes stored as text. Generated code converts time.Time fields,
+// e.g., (*NullableTime)(&o.Modified)
+type NullableTime time.Time
+
+// Value satisfies the driver.Valuer interface
+func (n NullableTime) Value() (driver.Value, error) {
+	return time.Time(n), nil
+}
+
+// Scan satisfies the sql.Scanner interface
+func (n *NullableTime) Scan(src interface{}) error {
+	t, err := scanTime(src)
+	if err != nil {
+		return err
+	}
+	*n = NullableTime(t)
+	return nil
+}
+
+// timeFormats are the datetime text formats recognized by scanTime
+var timeFormats = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	time.RFC3339Nano,
+}
+
+// scanTime converts a scanned column value to a time.Time.
+// A NULL column is the zero time, and integers are epoch seconds
+func scanTime(src interface{}) (time.Time, error) {
+	switch v := src.(type) {
+	case nil:
+		return time.Time{}, nil
+	case time.Time:
+		return v, nil
+	case int64:
+		return time.Unix(v, 0), nil
+	case float64:
+		return time.Unix(int64(v), 0), nil
+	case []byte:
+		return parseTime(string(v))
+	case string:
+		return parseTime(v)
+	}
+	return time.Time{}, fmt.Errorf("cannot scan %T into a time", src)
+}
+
+func parseTime(s string) (time.Time, error) {
+	s = strings.TrimSuffix(strings.TrimSpace(s), "Z")
+	if s == "" {
+		return time.Time{}, nil
+	}
+	for _, format := range timeFormats {
+		if t, err := time.ParseInLocation(format, s, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid datetime %q", s)
+}
+
+// converter holds the conversions registered for a Go type
+type converter struct {
+	valuer  func(interface{}) (driver.Value, error)
+	scanner func(*interface{}, interface{}) error
+}
+
+var (
+	convertersMu sync.RWMutex
+	converters   = make(map[reflect.Type]converter)
+)
+
+// RegisterConverter registers conversions for columns of goType, e.g., a net.IP stored as text.
+// The valuer converts a goType value for the driver, and the scanner sets its first argument
+// to the goType value of the scanned column. The Object* reflection methods consult
+// the converters, and generated or hand written code can use Converted and ConvertedPtr
+func RegisterConverter(goType reflect.Type, valuer func(interface{}) (driver.Value, error), scanner func(*interface{}, interface{}) error) {
+	convertersMu.Lock()
+	converters[goType] = converter{valuer: valuer, scanner: scanner}
+	convertersMu.Unlock()
+}
+
+func lookupConverter(t reflect.Type) (converter, bool) {
+	convertersMu.RLock()
+	c, ok := converters[t]
+	convertersMu.RUnlock()
+	return c, ok
+}
+
+// Converted returns v as a driver.Valuer using its registered converter, if any
+func Converted(v interface{}) interface{} {
+	if v == nil {
+		return v
+	}
+	if c, ok := lookupConverter(reflect.TypeOf(v)); ok {
+		return convertedValue{v: v, c: c}
+	}
+	return v
+}
+
+// ConvertedPtr returns ptr, a pointer to a scan destination, as an sql.Scanner
+// using the registered converter of the type it points to, if any
+func ConvertedPtr(ptr interface{}) interface{} {
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return ptr
+	}
+	if c, ok := lookupConverter(rv.Type().Elem()); ok {
+		return &convertedScanner{dest: rv.Elem(), c: c}
+	}
+	return ptr
+}
+
+type convertedValue struct {
+	v interface{}
+	c converter
+}
+
+// Value satisfies the driver.Valuer interface
+func (cv convertedValue) Value() (driver.Value, error) {
+	return cv.c.valuer(cv.v)
+}
+
+type convertedScanner struct {
+	dest reflect.Value
+	c    converter
+}
+
+// Scan satisfies the sql.Scanner interface
+func (cs *convertedScanner) Scan(src interface{}) error {
+	var out interface{}
+	if err := cs.c.scanner(&out, src); err != nil {
+		return err
+	}
+	if out == nil {
+		cs.dest.Set(reflect.Zero(cs.dest.Type()))
+		return nil
+	}
+	v := reflect.ValueOf(out)
+	if !v.Type().AssignableTo(cs.dest.Type()) {
+		return fmt.Errorf("converter returned %T for %s", out, cs.dest.Type())
+	}
+	cs.dest.Set(v)
+	return nil
+}
+
+// memberValue returns the value a member pointer refers to, as stored in its column
+func memberValue(ptr interface{}) (interface{}, error) {
+	switch p := ptr.(type) {
+	case *convertedScanner:
+		return p.c.valuer(p.dest.Interface())
+	case driver.Valuer:
+		return p.Value()
+	}
+	rv := reflect.ValueOf(ptr)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	return rv.Interface(), nil
+}
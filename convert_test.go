@@ -0,0 +1,214 @@
+package dbobj
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUnixTime(t *testing.T) {
+	db := structDBU(t)
+	if _, _, err := db.Exec("create table epochs (id integer primary key, stamp integer)"); err != nil {
+		t.Fatal(err)
+	}
+	when := time.Date(2020, 9, 16, 12, 30, 0, 0, time.UTC)
+	if _, _, err := db.Exec("insert into epochs (id, stamp) values(?,?)", 1, UnixTime(when)); err != nil {
+		t.Fatal(err)
+	}
+	var secs int64
+	if err := db.DB().QueryRow("select stamp from epochs where id=1").Scan(&secs); err != nil {
+		t.Fatal(err)
+	}
+	if secs != when.Unix() {
+		t.Fatalf("expected epoch %d, got %d", when.Unix(), secs)
+	}
+	var got time.Time
+	if err := db.DB().QueryRow("select stamp from epochs where id=1").Scan((*UnixTime)(&got)); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(when) {
+		t.Errorf("expected %v, got %v", when, got)
+	}
+}
+
+func TestUnixTimeNull(t *testing.T) {
+	var got time.Time
+	if err := (*UnixTime)(&got).Scan(nil); err != nil {
+		t.Fatal(err)
+	}
+	if !got.IsZero() {
+		t.Errorf("expected zero time, got %v", got)
+	}
+	if v, _ := UnixTime(got).Value(); v != nil {
+		t.Errorf("expected nil value for zero time, got %v", v)
+	}
+}
+
+func TestScanTime(t *testing.T) {
+	want := time.Date(2020, 6, 18, 11, 49, 33, 0, time.UTC)
+	for _, src := range []interface{}{
+		want,
+		want.Unix(),
+		"2020-06-18 11:49:33",
+		"2020-06-18T11:49:33Z",
+		[]byte("2020-06-18 11:49:33.000+00:00"),
+		"2020-06-18 11:49:33 +0000 UTC",
+	} {
+		var n NullableTime
+		if err := n.Scan(src); err != nil {
+			t.Errorf("scan %v: %v", src, err)
+			continue
+		}
+		if got := time.Time(n); !got.Equal(want) {
+			t.Errorf("scan %v: expected %v, got %v", src, want, got)
+		}
+	}
+	var n NullableTime
+	if err := n.Scan(nil); err != nil || !time.Time(n).IsZero() {
+		t.Errorf("expected NULL to scan as the zero time, got %v (%v)", time.Time(n), err)
+	}
+	if err := n.Scan("yesterday"); err == nil {
+		t.Error("expected error for invalid datetime")
+	}
+}
+
+func TestBlob(t *testing.T) {
+	v, err := Blob("\xff\x00").Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, ok := v.([]byte); !ok || string(b) != "\xff\x00" {
+		t.Errorf("expected bytes, got %#v", v)
+	}
+	var b Blob
+	for _, src := range []interface{}{[]byte("\xff\x00"), "\xff\x00"} {
+		if err := b.Scan(src); err != nil {
+			t.Fatal(err)
+		}
+		if b != "\xff\x00" {
+			t.Errorf("unexpected scan of %#v: %q", src, b)
+		}
+	}
+	if err := b.Scan(nil); err != nil || b != "" {
+		t.Errorf("expected NULL to scan as empty, got %q, %v", b, err)
+	}
+	if err := b.Scan(42); err == nil {
+		t.Error("expected error scanning an integer")
+	}
+}
+
+func TestDelimited(t *testing.T) {
+	var tags []string
+	d := &Delimited{Strings: &tags, Sep: "|"}
+	if err := d.Scan([]byte("x|y")); err != nil {
+		t.Fatal(err)
+	}
+	if !StringsEqual(tags, []string{"x", "y"}) {
+		t.Errorf("unexpected scan: %#v", tags)
+	}
+	if v, err := d.Value(); err != nil || v != "x|y" {
+		t.Errorf("expected x|y, got %v, %v", v, err)
+	}
+	for _, src := range []interface{}{nil, "", []byte{}} {
+		if err := d.Scan(src); err != nil {
+			t.Fatal(err)
+		}
+		if tags == nil || len(tags) != 0 {
+			t.Errorf("expected %#v to scan as an empty slice, got %#v", src, tags)
+		}
+	}
+	if err := d.Scan(42); err == nil {
+		t.Error("expected error scanning an integer")
+	}
+}
+
+func TestOmitEmpty(t *testing.T) {
+	for _, v := range []interface{}{nil, 0, "", NullableTime{}, false} {
+		if got := OmitEmpty(v); got != (omitted{}) {
+			t.Errorf("expected omitted for %#v, got %#v", v, got)
+		}
+	}
+	for _, v := range []interface{}{1, "x", true} {
+		if got := OmitEmpty(v); got != v {
+			t.Errorf("expected %#v, got %#v", v, got)
+		}
+	}
+}
+
+// hostStruct has a column needing a registered converter
+type hostStruct struct {
+	ID   int64  `sql:"id" key:"true" table:"hosts"`
+	Name string `sql:"name"`
+	IP   net.IP `sql:"ip"`
+}
+
+func TestRegisterConverter(t *testing.T) {
+	ipType := reflect.TypeOf(net.IP{})
+	RegisterConverter(ipType,
+		func(v interface{}) (driver.Value, error) {
+			return v.(net.IP).String(), nil
+		},
+		func(dest *interface{}, src interface{}) error {
+			var s string
+			switch v := src.(type) {
+			case nil:
+				return nil
+			case string:
+				s = v
+			case []byte:
+				s = string(v)
+			default:
+				return fmt.Errorf("cannot scan %T into net.IP", src)
+			}
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return fmt.Errorf("invalid ip: %q", s)
+			}
+			*dest = ip
+			return nil
+		},
+	)
+	defer func() {
+		convertersMu.Lock()
+		delete(converters, ipType)
+		convertersMu.Unlock()
+	}()
+
+	db := structDBU(t)
+	if _, _, err := db.Exec("create table hosts (id integer primary key, name text, ip text)"); err != nil {
+		t.Fatal(err)
+	}
+	h := hostStruct{Name: "router", IP: net.ParseIP("10.0.0.1")}
+	id, err := db.ObjectInsert(&h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw string
+	if err := db.ScanValue(&raw, "select ip from hosts where id=?", id); err != nil {
+		t.Fatal(err)
+	}
+	if raw != "10.0.0.1" {
+		t.Errorf("expected ip stored as text, got %q", raw)
+	}
+	got := hostStruct{}
+	if err := db.ObjectLoad(&got, "where id=?", id); err != nil {
+		t.Fatal(err)
+	}
+	if !got.IP.Equal(h.IP) {
+		t.Errorf("expected %v, got %v", h.IP, got.IP)
+	}
+	got.IP = net.ParseIP("10.0.0.2")
+	if err := db.ObjectUpdate(&got); err != nil {
+		t.Fatal(err)
+	}
+	var hosts []hostStruct
+	if err := db.QueryStructs(&hosts, "select id, name, ip from hosts"); err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != 1 || !hosts[0].IP.Equal(got.IP) {
+		t.Errorf("expected updated ip, got %v", hosts)
+	}
+}
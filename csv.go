@@ -0,0 +1,159 @@
+package dbobj
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ExportCSV streams the objects matching the optional where clause to w as CSV,
+// with a header of the object's sql columns followed by one row per record
+func (du *DBU) ExportCSV(w io.Writer, o DBObject, where string, args ...interface{}) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(selectColumns(o)); err != nil {
+		return err
+	}
+	it, err := du.Iterate(o, where, args...)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+	obj := newObject(o)
+	dest := obj.MemberPointers()
+	record := make([]string, len(dest))
+	for it.Next() {
+		if err := it.Scan(obj); err != nil {
+			return err
+		}
+		for i, ptr := range dest {
+			if record[i], err = csvValue(ptr); err != nil {
+				return err
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvValue formats the value a member pointer refers to as a CSV field
+func csvValue(ptr interface{}) (string, error) {
+	v, err := memberValue(ptr)
+	if err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case []byte:
+		return string(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339), nil
+	}
+	return fmt.Sprint(v), nil
+}
+
+// SetSkipUnknownColumns sets whether ImportCSV skips header columns the object
+// does not declare, rather than returning ErrUnknownColumn
+func (du *DBU) SetSkipUnknownColumns(skip bool) {
+	du.skipUnknown = skip
+}
+
+// ImportCSV inserts the records of a CSV, whose header names the object's sql columns,
+// into the object's table in a single transaction, returning the number inserted.
+// Fields are inserted as text, leaving the conversion to the column's type affinity
+func (du *DBU) ImportCSV(r io.Reader, o DBObject) (int64, error) {
+	if du.readOnly {
+		return 0, ErrReadOnly
+	}
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	known := make(map[string]bool)
+	for _, col := range selectColumns(o) {
+		known[col] = true
+	}
+	var cols []string
+	var index []int
+	for i, col := range header {
+		col = strings.TrimSpace(col)
+		if !known[col] {
+			if du.skipUnknown {
+				continue
+			}
+			return 0, errors.Wrapf(ErrUnknownColumn, "table: %s column: %q", tableName(o), col)
+		}
+		cols = append(cols, du.dialect.Quote(col))
+		index = append(index, i)
+	}
+	if len(cols) == 0 {
+		return 0, errors.Errorf("table: %s no columns in csv header", tableName(o))
+	}
+	query := fmt.Sprintf("insert into %s (%s) values (%s)", du.dialect.Quote(tableName(o)), strings.Join(cols, ","), du.dialect.Placeholders(1, len(cols)))
+	if du.db == nil && !du.dryRun {
+		return 0, du.noTx()
+	}
+	var tx *sql.Tx
+	var stmt *sql.Stmt
+	ctx, cancel := sqlWrapper{du.db, du.queryTimeout}.context()
+	defer cancel()
+	if !du.dryRun {
+		if tx, err = du.db.BeginTx(ctx, nil); err != nil {
+			return 0, err
+		}
+		if stmt, err = tx.PrepareContext(ctx, query); err != nil {
+			if e := tx.Rollback(); e != nil && e != sql.ErrTxDone {
+				log.Printf("prepare rollback error: %v\n", e)
+			}
+			return 0, err
+		}
+		defer stmt.Close()
+	}
+	var count int64
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			args := make([]interface{}, len(index))
+			for i, j := range index {
+				args[i] = record[j]
+			}
+			if du.dryRun {
+				du.debugf("DRY RUN Q: %s A: %v\n", query, args)
+			} else {
+				_, err = stmt.ExecContext(ctx, args...)
+			}
+		}
+		if err != nil {
+			if tx != nil {
+				if e := tx.Rollback(); e != nil && e != sql.ErrTxDone {
+					log.Printf("exec rollback error: %v\n", e)
+				}
+			}
+			return 0, errors.Wrapf(err, "record: %d", count+1)
+		}
+		count++
+	}
+	if tx == nil {
+		return count, nil
+	}
+	return count, tx.Commit()
+}
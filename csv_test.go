@@ -0,0 +1,80 @@
+package dbobj
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestExportCSV(t *testing.T) {
+	db := structDBU(t)
+	var count int
+	if err := db.ScanValue(&count, "select count(*) from structs"); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := db.ExportCSV(&buf, &testStruct{}, ""); err != nil {
+		t.Fatal(err)
+	}
+	header, _, _ := strings.Cut(buf.String(), "\n")
+	if header != "id,name,kind,data,modified" {
+		t.Errorf("unexpected header: %q", header)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records)-1 != count {
+		t.Errorf("expected %d rows, got %d", count, len(records)-1)
+	}
+	if records[1][0] != "1" || records[1][1] != "abc" {
+		t.Errorf("unexpected first row: %v", records[1])
+	}
+
+	buf.Reset()
+	if err := db.ExportCSV(&buf, &testStruct{}, "kind=?", -1); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(buf.String(), "\n"); got != 1 {
+		t.Errorf("expected only the header, got %d lines", got)
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	db := structDBU(t)
+	const data = "name,kind,data\nimported,42,\"x,y\"\nsecond,43,z\n"
+	n, err := db.ImportCSV(strings.NewReader(data), &testStruct{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 rows imported, got %d", n)
+	}
+	s := testStruct{}
+	if err := db.FindWhere(&s, "name=?", "imported"); err != nil {
+		t.Fatal(err)
+	}
+	if s.Kind != 42 || s.Data != "x,y" {
+		t.Errorf("unexpected import: %+v", s)
+	}
+
+	const unknown = "name,kind,bogus\nother,44,b\n"
+	if _, err := db.ImportCSV(strings.NewReader(unknown), &testStruct{}); errors.Cause(err) != ErrUnknownColumn {
+		t.Errorf("expected ErrUnknownColumn, got %v", err)
+	}
+	db.SetSkipUnknownColumns(true)
+	if n, err := db.ImportCSV(strings.NewReader(unknown), &testStruct{}); err != nil || n != 1 {
+		t.Errorf("expected 1 row imported skipping bogus, got %d: %v", n, err)
+	}
+
+	const short = "name,kind\nbad,45\ntruncated\n"
+	if _, err := db.ImportCSV(strings.NewReader(short), &testStruct{}); err == nil {
+		t.Error("expected error for a short record")
+	}
+	if ok, err := db.Exists(&testStruct{}, "name=?", "bad"); err != nil || ok {
+		t.Errorf("expected the failed import to be rolled back: %v", err)
+	}
+}
@@ -0,0 +1,1233 @@
+// generated by 'dbgen -stringer -registry -json -output generated_test.go -type testStruct,auditStruct,listStruct,stampStruct,userStruct,rowidStruct,groupStruct struct_test.go'; DO NOT EDIT
+
+package main
+
+import (
+	"time"
+
+	"github.com/paulstuart/dbobj"
+)
+
+// in case time isn't otherwise referenced
+var _ = time.Now()
+
+// testKind is an enumerated type stored as an integer column
+type testKind int
+
+func (e testKind) Value() (dbobj.Value, error) {
+	return int64(e), nil
+}
+
+func (e *testKind) Scan(src interface{}) error {
+	i, err := dbobj.ScanInt(src)
+	*e = testKind(i)
+	return err
+}
+
+// testStruct DBObject generator
+var _ dbobj.DBObject = (*testStruct)(nil)
+
+func (o *testStruct) NewObj() interface{} {
+	return new(testStruct)
+}
+
+// testStruct DBObject interface functions
+func (o *testStruct) InsertValues() []interface{} {
+	return []interface{}{o.Name, o.Kind, o.Data, dbobj.NullableTime(o.Created)}
+}
+func (o *testStruct) UpdateValues() []interface{} {
+	return []interface{}{o.Name, o.Kind, o.Data, dbobj.NullableTime(o.Created), o.ID}
+}
+
+func (o *testStruct) MemberPointers() []interface{} {
+	return []interface{}{&o.ID, &o.Name, &o.Kind, &o.Data, (*dbobj.NullableTime)(&o.Created)}
+}
+
+func (o *testStruct) FromRow(r dbobj.RowScanner) error {
+	return r.Scan(o.MemberPointers()...)
+}
+
+func (o *testStruct) Key() int64 {
+	return o.ID
+}
+
+func (o *testStruct) SetID(id int64) {
+	o.ID = id
+}
+
+func (o *testStruct) SQLGet(keys ...interface{}) string {
+	return "select id,name,kind,data,created from teststruct where ;"
+}
+
+func (o *testStruct) TableName() string {
+	return "teststruct"
+}
+
+func (o *testStruct) SelectFields() string {
+	return "id,name,kind,data,created"
+}
+
+func (o *testStruct) QualifiedSelectFields() string {
+	return "teststruct.id,teststruct.name,teststruct.kind,teststruct.data,teststruct.created"
+}
+
+func (o *testStruct) InsertFields() string {
+	return "id,name,kind,data,created"
+}
+
+func (o *testStruct) KeyField() string {
+	return "id"
+}
+
+func (o *testStruct) KeyName() string {
+	return "ID"
+}
+
+func (o *testStruct) AutoIncrement() bool {
+	return true
+}
+
+func (o *testStruct) Names() []string {
+	return []string{"Name", "Kind", "Data", "Created"}
+}
+
+func (o *testStruct) Columns() []string {
+	return []string{"id", "name", "kind", "data", "created"}
+}
+
+func (o *testStruct) SchemaQuery() string {
+	return "create table if not exists teststruct (id integer not null primary key, name text, kind integer, data blob, created datetime default CURRENT_TIMESTAMP)"
+}
+
+func (o *testStruct) TableInfo() dbobj.TableMeta {
+	return dbobj.TableMeta{
+		Table: "teststruct",
+		Key:   "id",
+		Fields: []dbobj.FieldMeta{
+			{GoName: "ID", SQLName: "id", GoType: "int64"},
+			{GoName: "Name", SQLName: "name", GoType: "string"},
+			{GoName: "Kind", SQLName: "kind", GoType: "testKind"},
+			{GoName: "Data", SQLName: "data", GoType: "[]byte"},
+			{GoName: "Created", SQLName: "created", GoType: "time.Time"},
+		},
+	}
+}
+
+func (o *testStruct) FieldMap() map[string]interface{} {
+	return map[string]interface{}{
+		"id":      &o.ID,
+		"name":    &o.Name,
+		"kind":    &o.Kind,
+		"data":    &o.Data,
+		"created": (*dbobj.NullableTime)(&o.Created),
+	}
+}
+
+func (o *testStruct) Clone() *testStruct {
+	c := new(testStruct)
+	c.ID = o.ID
+	c.Name = o.Name
+	c.Kind = o.Kind
+	c.Data = append([]byte(nil), o.Data...)
+	c.Created = o.Created
+	return c
+}
+
+func (o *testStruct) Equal(other *testStruct) bool {
+	if o == nil || other == nil {
+		return o == other
+	}
+	return o.ID == other.ID &&
+		o.Name == other.Name &&
+		o.Kind == other.Kind &&
+		string(o.Data) == string(other.Data) &&
+		o.Created.Equal(other.Created)
+}
+
+func (o *testStruct) KeyFields() []string {
+	return []string{"id"}
+}
+
+func (o *testStruct) KeyValues() []interface{} {
+	return []interface{}{o.ID}
+}
+
+func (o *testStruct) UpdateFieldQuery(d dbobj.Dialect, table string, cols ...string) (string, []interface{}, error) {
+	query := "update " + d.Quote(table) + " set "
+	args := make([]interface{}, 0, len(cols)+1)
+	for i, col := range cols {
+		switch col {
+		case "name":
+			args = append(args, o.Name)
+		case "kind":
+			args = append(args, o.Kind)
+		case "data":
+			args = append(args, o.Data)
+		default:
+			return "", nil, dbobj.UnknownColumn(table, col)
+		}
+		if i > 0 {
+			query += ","
+		}
+		query += d.Quote(col) + "=" + d.Placeholder(len(args))
+	}
+	return query + " where " + d.Quote("id") + "=" + d.Placeholder(len(args)+1), append(args, o.ID), nil
+}
+
+func (o *testStruct) String() string {
+	return dbobj.FormatObject("teststruct", "id,name,kind,data,created", o.ID, o.Name, o.Kind, o.Data, o.Created)
+}
+
+func (o *testStruct) MarshalJSON() ([]byte, error) {
+	return dbobj.MarshalColumns(o.Columns(), o.ID, o.Name, o.Kind, o.Data, o.Created)
+}
+
+func (o *testStruct) UnmarshalJSON(data []byte) error {
+	return dbobj.UnmarshalColumns(data, map[string]interface{}{
+		"id":      &o.ID,
+		"name":    &o.Name,
+		"kind":    &o.Kind,
+		"data":    &o.Data,
+		"created": &o.Created,
+	})
+}
+
+func (o *testStruct) Validate() error {
+	if len(o.Name) > 255 {
+		return dbobj.Invalid("teststruct", "name", "exceeds max length 255")
+	}
+	if o.Kind < 0 {
+		return dbobj.Invalid("teststruct", "kind", "less than minimum 0")
+	}
+	return nil
+}
+
+func (o *testStruct) ModifiedBy(user int64, t time.Time) {
+	o.Created = t
+}
+
+// auditStruct DBObject generator
+var _ dbobj.DBObject = (*auditStruct)(nil)
+
+func (o *auditStruct) NewObj() interface{} {
+	return new(auditStruct)
+}
+
+// auditStruct DBObject interface functions
+func (o *auditStruct) InsertValues() []interface{} {
+	return []interface{}{o.UserID, o.Modified}
+}
+func (o *auditStruct) UpdateValues() []interface{} {
+	return []interface{}{o.UserID, o.Modified, o.ID}
+}
+
+func (o *auditStruct) MemberPointers() []interface{} {
+	return []interface{}{&o.ID, &o.UserID, &o.Modified}
+}
+
+func (o *auditStruct) FromRow(r dbobj.RowScanner) error {
+	return r.Scan(o.MemberPointers()...)
+}
+
+func (o *auditStruct) Key() int64 {
+	return o.ID
+}
+
+func (o *auditStruct) SetID(id int64) {
+	o.ID = id
+}
+
+func (o *auditStruct) SQLGet(keys ...interface{}) string {
+	return "select id,userid,modified from audits where ;"
+}
+
+func (o *auditStruct) TableName() string {
+	return "audits"
+}
+
+func (o *auditStruct) SelectFields() string {
+	return "id,userid,modified"
+}
+
+func (o *auditStruct) QualifiedSelectFields() string {
+	return "audits.id,audits.userid,audits.modified"
+}
+
+func (o *auditStruct) InsertFields() string {
+	return "id,userid,modified"
+}
+
+func (o *auditStruct) KeyField() string {
+	return "id"
+}
+
+func (o *auditStruct) KeyName() string {
+	return "ID"
+}
+
+func (o *auditStruct) AutoIncrement() bool {
+	return true
+}
+
+func (o *auditStruct) Names() []string {
+	return []string{"UserID", "Modified"}
+}
+
+func (o *auditStruct) Columns() []string {
+	return []string{"id", "userid", "modified"}
+}
+
+func (o *auditStruct) SchemaQuery() string {
+	return "create table if not exists audits (id integer not null primary key, userid integer, modified datetime)"
+}
+
+func (o *auditStruct) TableInfo() dbobj.TableMeta {
+	return dbobj.TableMeta{
+		Table: "audits",
+		Key:   "id",
+		Fields: []dbobj.FieldMeta{
+			{GoName: "ID", SQLName: "id", GoType: "int64"},
+			{GoName: "UserID", SQLName: "userid", GoType: "*int64"},
+			{GoName: "Modified", SQLName: "modified", GoType: "*time.Time"},
+		},
+	}
+}
+
+func (o *auditStruct) FieldMap() map[string]interface{} {
+	return map[string]interface{}{
+		"id":       &o.ID,
+		"userid":   &o.UserID,
+		"modified": &o.Modified,
+	}
+}
+
+func (o *auditStruct) Clone() *auditStruct {
+	c := new(auditStruct)
+	c.ID = o.ID
+	if o.UserID != nil {
+		v := *o.UserID
+		c.UserID = &v
+	}
+	if o.Modified != nil {
+		v := *o.Modified
+		c.Modified = &v
+	}
+	return c
+}
+
+func (o *auditStruct) Equal(other *auditStruct) bool {
+	if o == nil || other == nil {
+		return o == other
+	}
+	return o.ID == other.ID &&
+		(o.UserID == nil) == (other.UserID == nil) && (o.UserID == nil || *o.UserID == *other.UserID) &&
+		(o.Modified == nil) == (other.Modified == nil) && (o.Modified == nil || o.Modified.Equal(*other.Modified))
+}
+
+func (o *auditStruct) KeyFields() []string {
+	return []string{"id"}
+}
+
+func (o *auditStruct) KeyValues() []interface{} {
+	return []interface{}{o.ID}
+}
+
+func (o *auditStruct) UpdateFieldQuery(d dbobj.Dialect, table string, cols ...string) (string, []interface{}, error) {
+	query := "update " + d.Quote(table) + " set "
+	args := make([]interface{}, 0, len(cols)+1)
+	for i, col := range cols {
+		switch col {
+		case "userid":
+			args = append(args, o.UserID)
+		case "modified":
+			args = append(args, o.Modified)
+		default:
+			return "", nil, dbobj.UnknownColumn(table, col)
+		}
+		if i > 0 {
+			query += ","
+		}
+		query += d.Quote(col) + "=" + d.Placeholder(len(args))
+	}
+	return query + " where " + d.Quote("id") + "=" + d.Placeholder(len(args)+1), append(args, o.ID), nil
+}
+
+func (o *auditStruct) String() string {
+	return dbobj.FormatObject("audits", "id,userid,modified", o.ID, o.UserID, o.Modified)
+}
+
+func (o *auditStruct) MarshalJSON() ([]byte, error) {
+	return dbobj.MarshalColumns(o.Columns(), o.ID, o.UserID, o.Modified)
+}
+
+func (o *auditStruct) UnmarshalJSON(data []byte) error {
+	return dbobj.UnmarshalColumns(data, map[string]interface{}{
+		"id":       &o.ID,
+		"userid":   &o.UserID,
+		"modified": &o.Modified,
+	})
+}
+
+func (o *auditStruct) ModifiedBy(user int64, t time.Time) {
+	o.UserID = &user
+	o.Modified = &t
+}
+
+// listStruct DBObject generator
+var _ dbobj.DBObject = (*listStruct)(nil)
+
+func (o *listStruct) NewObj() interface{} {
+	return new(listStruct)
+}
+
+// listStruct DBObject interface functions
+func (o *listStruct) InsertValues() []interface{} {
+	return []interface{}{dbobj.Delimited{Strings: &o.Tags, Sep: ","}}
+}
+func (o *listStruct) UpdateValues() []interface{} {
+	return []interface{}{dbobj.Delimited{Strings: &o.Tags, Sep: ","}, o.ID}
+}
+
+func (o *listStruct) MemberPointers() []interface{} {
+	return []interface{}{&o.ID, &dbobj.Delimited{Strings: &o.Tags, Sep: ","}}
+}
+
+func (o *listStruct) FromRow(r dbobj.RowScanner) error {
+	return r.Scan(o.MemberPointers()...)
+}
+
+func (o *listStruct) Key() int64 {
+	return o.ID
+}
+
+func (o *listStruct) SetID(id int64) {
+	o.ID = id
+}
+
+func (o *listStruct) SQLGet(keys ...interface{}) string {
+	return "select id,tags from lists where ;"
+}
+
+func (o *listStruct) TableName() string {
+	return "lists"
+}
+
+func (o *listStruct) SelectFields() string {
+	return "id,tags"
+}
+
+func (o *listStruct) QualifiedSelectFields() string {
+	return "lists.id,lists.tags"
+}
+
+func (o *listStruct) InsertFields() string {
+	return "id,tags"
+}
+
+func (o *listStruct) KeyField() string {
+	return "id"
+}
+
+func (o *listStruct) KeyName() string {
+	return "ID"
+}
+
+func (o *listStruct) AutoIncrement() bool {
+	return true
+}
+
+func (o *listStruct) Names() []string {
+	return []string{"Tags"}
+}
+
+func (o *listStruct) Columns() []string {
+	return []string{"id", "tags"}
+}
+
+func (o *listStruct) SchemaQuery() string {
+	return "create table if not exists lists (id integer not null primary key, tags text)"
+}
+
+func (o *listStruct) TableInfo() dbobj.TableMeta {
+	return dbobj.TableMeta{
+		Table: "lists",
+		Key:   "id",
+		Fields: []dbobj.FieldMeta{
+			{GoName: "ID", SQLName: "id", GoType: "int64"},
+			{GoName: "Tags", SQLName: "tags", GoType: "[]string"},
+		},
+	}
+}
+
+func (o *listStruct) FieldMap() map[string]interface{} {
+	return map[string]interface{}{
+		"id":   &o.ID,
+		"tags": &dbobj.Delimited{Strings: &o.Tags, Sep: ","},
+	}
+}
+
+func (o *listStruct) Clone() *listStruct {
+	c := new(listStruct)
+	c.ID = o.ID
+	c.Tags = append([]string(nil), o.Tags...)
+	return c
+}
+
+func (o *listStruct) Equal(other *listStruct) bool {
+	if o == nil || other == nil {
+		return o == other
+	}
+	return o.ID == other.ID &&
+		dbobj.StringsEqual(o.Tags, other.Tags)
+}
+
+func (o *listStruct) KeyFields() []string {
+	return []string{"id"}
+}
+
+func (o *listStruct) KeyValues() []interface{} {
+	return []interface{}{o.ID}
+}
+
+func (o *listStruct) UpdateFieldQuery(d dbobj.Dialect, table string, cols ...string) (string, []interface{}, error) {
+	query := "update " + d.Quote(table) + " set "
+	args := make([]interface{}, 0, len(cols)+1)
+	for i, col := range cols {
+		switch col {
+		case "tags":
+			args = append(args, dbobj.Delimited{Strings: &o.Tags, Sep: ","})
+		default:
+			return "", nil, dbobj.UnknownColumn(table, col)
+		}
+		if i > 0 {
+			query += ","
+		}
+		query += d.Quote(col) + "=" + d.Placeholder(len(args))
+	}
+	return query + " where " + d.Quote("id") + "=" + d.Placeholder(len(args)+1), append(args, o.ID), nil
+}
+
+func (o *listStruct) String() string {
+	return dbobj.FormatObject("lists", "id,tags", o.ID, o.Tags)
+}
+
+func (o *listStruct) MarshalJSON() ([]byte, error) {
+	return dbobj.MarshalColumns(o.Columns(), o.ID, o.Tags)
+}
+
+func (o *listStruct) UnmarshalJSON(data []byte) error {
+	return dbobj.UnmarshalColumns(data, map[string]interface{}{
+		"id":   &o.ID,
+		"tags": &o.Tags,
+	})
+}
+
+func (o *listStruct) ModifiedBy(user int64, t time.Time) {
+}
+
+// stampStruct DBObject generator
+var _ dbobj.DBObject = (*stampStruct)(nil)
+
+func (o *stampStruct) NewObj() interface{} {
+	return new(stampStruct)
+}
+
+// stampStruct DBObject interface functions
+func (o *stampStruct) InsertValues() []interface{} {
+	return []interface{}{o.Name, o.Creator, dbobj.NullableTime(o.Created), o.Updater, dbobj.NullableTime(o.Updated)}
+}
+func (o *stampStruct) UpdateValues() []interface{} {
+	return []interface{}{o.Name, o.Creator, dbobj.NullableTime(o.Created), o.Updater, dbobj.NullableTime(o.Updated), o.ID}
+}
+
+func (o *stampStruct) MemberPointers() []interface{} {
+	return []interface{}{&o.ID, &o.Name, &o.Creator, (*dbobj.NullableTime)(&o.Created), &o.Updater, (*dbobj.NullableTime)(&o.Updated)}
+}
+
+func (o *stampStruct) FromRow(r dbobj.RowScanner) error {
+	return r.Scan(o.MemberPointers()...)
+}
+
+func (o *stampStruct) Key() int64 {
+	return o.ID
+}
+
+func (o *stampStruct) SetID(id int64) {
+	o.ID = id
+}
+
+func (o *stampStruct) SQLGet(keys ...interface{}) string {
+	return "select id,name,created_by,created_at,updated_by,updated_at from stamps where ;"
+}
+
+func (o *stampStruct) TableName() string {
+	return "stamps"
+}
+
+func (o *stampStruct) SelectFields() string {
+	return "id,name,created_by,created_at,updated_by,updated_at"
+}
+
+func (o *stampStruct) QualifiedSelectFields() string {
+	return "stamps.id,stamps.name,stamps.created_by,stamps.created_at,stamps.updated_by,stamps.updated_at"
+}
+
+func (o *stampStruct) InsertFields() string {
+	return "id,name,created_by,created_at,updated_by,updated_at"
+}
+
+func (o *stampStruct) KeyField() string {
+	return "id"
+}
+
+func (o *stampStruct) KeyName() string {
+	return "ID"
+}
+
+func (o *stampStruct) AutoIncrement() bool {
+	return true
+}
+
+func (o *stampStruct) Names() []string {
+	return []string{"Name", "Creator", "Created", "Updater", "Updated"}
+}
+
+func (o *stampStruct) Columns() []string {
+	return []string{"id", "name", "created_by", "created_at", "updated_by", "updated_at"}
+}
+
+func (o *stampStruct) SchemaQuery() string {
+	return "create table if not exists stamps (id integer not null primary key, name text, created_by integer, created_at datetime, updated_by integer, updated_at datetime)"
+}
+
+func (o *stampStruct) TableInfo() dbobj.TableMeta {
+	return dbobj.TableMeta{
+		Table: "stamps",
+		Key:   "id",
+		Fields: []dbobj.FieldMeta{
+			{GoName: "ID", SQLName: "id", GoType: "int64"},
+			{GoName: "Name", SQLName: "name", GoType: "string"},
+			{GoName: "Creator", SQLName: "created_by", GoType: "int64"},
+			{GoName: "Created", SQLName: "created_at", GoType: "time.Time"},
+			{GoName: "Updater", SQLName: "updated_by", GoType: "int64"},
+			{GoName: "Updated", SQLName: "updated_at", GoType: "time.Time"},
+		},
+	}
+}
+
+func (o *stampStruct) FieldMap() map[string]interface{} {
+	return map[string]interface{}{
+		"id":         &o.ID,
+		"name":       &o.Name,
+		"created_by": &o.Creator,
+		"created_at": (*dbobj.NullableTime)(&o.Created),
+		"updated_by": &o.Updater,
+		"updated_at": (*dbobj.NullableTime)(&o.Updated),
+	}
+}
+
+func (o *stampStruct) Clone() *stampStruct {
+	c := new(stampStruct)
+	c.ID = o.ID
+	c.Name = o.Name
+	c.Creator = o.Creator
+	c.Created = o.Created
+	c.Updater = o.Updater
+	c.Updated = o.Updated
+	return c
+}
+
+func (o *stampStruct) Equal(other *stampStruct) bool {
+	if o == nil || other == nil {
+		return o == other
+	}
+	return o.ID == other.ID &&
+		o.Name == other.Name &&
+		o.Creator == other.Creator &&
+		o.Created.Equal(other.Created) &&
+		o.Updater == other.Updater &&
+		o.Updated.Equal(other.Updated)
+}
+
+func (o *stampStruct) KeyFields() []string {
+	return []string{"id"}
+}
+
+func (o *stampStruct) KeyValues() []interface{} {
+	return []interface{}{o.ID}
+}
+
+func (o *stampStruct) UpdateFieldQuery(d dbobj.Dialect, table string, cols ...string) (string, []interface{}, error) {
+	query := "update " + d.Quote(table) + " set "
+	args := make([]interface{}, 0, len(cols)+1)
+	for i, col := range cols {
+		switch col {
+		case "name":
+			args = append(args, o.Name)
+		case "updated_by":
+			args = append(args, o.Updater)
+		case "updated_at":
+			args = append(args, dbobj.NullableTime(o.Updated))
+		default:
+			return "", nil, dbobj.UnknownColumn(table, col)
+		}
+		if i > 0 {
+			query += ","
+		}
+		query += d.Quote(col) + "=" + d.Placeholder(len(args))
+	}
+	return query + " where " + d.Quote("id") + "=" + d.Placeholder(len(args)+1), append(args, o.ID), nil
+}
+
+func (o *stampStruct) String() string {
+	return dbobj.FormatObject("stamps", "id,name,created_by,created_at,updated_by,updated_at", o.ID, o.Name, o.Creator, o.Created, o.Updater, o.Updated)
+}
+
+func (o *stampStruct) MarshalJSON() ([]byte, error) {
+	return dbobj.MarshalColumns(o.Columns(), o.ID, o.Name, o.Creator, o.Created, o.Updater, o.Updated)
+}
+
+func (o *stampStruct) UnmarshalJSON(data []byte) error {
+	return dbobj.UnmarshalColumns(data, map[string]interface{}{
+		"id":         &o.ID,
+		"name":       &o.Name,
+		"created_by": &o.Creator,
+		"created_at": &o.Created,
+		"updated_by": &o.Updater,
+		"updated_at": &o.Updated,
+	})
+}
+
+func (o *stampStruct) ModifiedBy(user int64, t time.Time) {
+	o.Updater = user
+	o.Updated = t
+}
+
+func (o *stampStruct) CreatedBy(user int64, t time.Time) {
+	o.Creator = user
+	o.Created = t
+}
+
+// userStruct DBObject generator
+var _ dbobj.DBObject = (*userStruct)(nil)
+
+func (o *userStruct) NewObj() interface{} {
+	return new(userStruct)
+}
+
+// userStruct DBObject interface functions
+func (o *userStruct) InsertValues() []interface{} {
+	return []interface{}{o.Email, o.Name}
+}
+func (o *userStruct) UpdateValues() []interface{} {
+	return []interface{}{o.Email, o.Name, o.ID}
+}
+
+func (o *userStruct) MemberPointers() []interface{} {
+	return []interface{}{&o.ID, &o.Email, &o.Name}
+}
+
+func (o *userStruct) FromRow(r dbobj.RowScanner) error {
+	return r.Scan(o.MemberPointers()...)
+}
+
+func (o *userStruct) Key() int64 {
+	return o.ID
+}
+
+func (o *userStruct) SetID(id int64) {
+	o.ID = id
+}
+
+func (o *userStruct) SQLGet(keys ...interface{}) string {
+	return "select id,email,name from users where ;"
+}
+
+func (o *userStruct) TableName() string {
+	return "users"
+}
+
+func (o *userStruct) SelectFields() string {
+	return "id,email,name"
+}
+
+func (o *userStruct) QualifiedSelectFields() string {
+	return "users.id,users.email,users.name"
+}
+
+func (o *userStruct) InsertFields() string {
+	return "id,email,name"
+}
+
+func (o *userStruct) KeyField() string {
+	return "id"
+}
+
+func (o *userStruct) KeyName() string {
+	return "ID"
+}
+
+func (o *userStruct) AutoIncrement() bool {
+	return true
+}
+
+func (o *userStruct) Names() []string {
+	return []string{"Email", "Name"}
+}
+
+func (o *userStruct) Columns() []string {
+	return []string{"id", "email", "name"}
+}
+
+func (o *userStruct) SchemaQuery() string {
+	return "create table if not exists users (id integer not null primary key, email text, name text, unique (email))"
+}
+
+func (o *userStruct) TableInfo() dbobj.TableMeta {
+	return dbobj.TableMeta{
+		Table: "users",
+		Key:   "id",
+		Fields: []dbobj.FieldMeta{
+			{GoName: "ID", SQLName: "id", GoType: "int64"},
+			{GoName: "Email", SQLName: "email", GoType: "string"},
+			{GoName: "Name", SQLName: "name", GoType: "string"},
+		},
+	}
+}
+
+func (o *userStruct) FieldMap() map[string]interface{} {
+	return map[string]interface{}{
+		"id":    &o.ID,
+		"email": &o.Email,
+		"name":  &o.Name,
+	}
+}
+
+func (o *userStruct) Clone() *userStruct {
+	c := new(userStruct)
+	c.ID = o.ID
+	c.Email = o.Email
+	c.Name = o.Name
+	return c
+}
+
+func (o *userStruct) Equal(other *userStruct) bool {
+	if o == nil || other == nil {
+		return o == other
+	}
+	return o.ID == other.ID &&
+		o.Email == other.Email &&
+		o.Name == other.Name
+}
+
+func (o *userStruct) KeyFields() []string {
+	return []string{"id"}
+}
+
+func (o *userStruct) KeyValues() []interface{} {
+	return []interface{}{o.ID}
+}
+
+func (o *userStruct) UpdateFieldQuery(d dbobj.Dialect, table string, cols ...string) (string, []interface{}, error) {
+	query := "update " + d.Quote(table) + " set "
+	args := make([]interface{}, 0, len(cols)+1)
+	for i, col := range cols {
+		switch col {
+		case "email":
+			args = append(args, o.Email)
+		case "name":
+			args = append(args, o.Name)
+		default:
+			return "", nil, dbobj.UnknownColumn(table, col)
+		}
+		if i > 0 {
+			query += ","
+		}
+		query += d.Quote(col) + "=" + d.Placeholder(len(args))
+	}
+	return query + " where " + d.Quote("id") + "=" + d.Placeholder(len(args)+1), append(args, o.ID), nil
+}
+
+func (o *userStruct) UpsertByUnique(d dbobj.Dialect, table, name string) (string, []interface{}) {
+	insert := "insert into " + d.Quote(table) + " (" + d.Quote("email") + "," + d.Quote("name") + ") values (" + d.Placeholders(1, 2) + ")"
+	args := []interface{}{o.Email, o.Name}
+	switch name {
+	case "email":
+		if d == dbobj.MySQL {
+			return insert + " on duplicate key update " + d.Quote("name") + "=values(" + d.Quote("name") + ")", args
+		}
+		return insert + " on conflict (" + d.Quote("email") + ") do update set " + d.Quote("name") + "=excluded." + d.Quote("name"), args
+	}
+	return "", nil
+}
+
+func (o *userStruct) UniqueFields(name string) []string {
+	switch name {
+	case "email":
+		return []string{"email"}
+	}
+	return nil
+}
+
+func (o *userStruct) String() string {
+	return dbobj.FormatObject("users", "id,email,name", o.ID, o.Email, o.Name)
+}
+
+func (o *userStruct) MarshalJSON() ([]byte, error) {
+	return dbobj.MarshalColumns(o.Columns(), o.ID, o.Email, o.Name)
+}
+
+func (o *userStruct) UnmarshalJSON(data []byte) error {
+	return dbobj.UnmarshalColumns(data, map[string]interface{}{
+		"id":    &o.ID,
+		"email": &o.Email,
+		"name":  &o.Name,
+	})
+}
+
+func (o *userStruct) ModifiedBy(user int64, t time.Time) {
+}
+
+// rowidStruct DBObject generator
+var _ dbobj.DBObject = (*rowidStruct)(nil)
+
+func (o *rowidStruct) NewObj() interface{} {
+	return new(rowidStruct)
+}
+
+// rowidStruct DBObject interface functions
+func (o *rowidStruct) InsertValues() []interface{} {
+	return []interface{}{o.Note}
+}
+func (o *rowidStruct) UpdateValues() []interface{} {
+	return []interface{}{o.Note, o.ID}
+}
+
+func (o *rowidStruct) MemberPointers() []interface{} {
+	return []interface{}{&o.ID, &o.Note}
+}
+
+func (o *rowidStruct) FromRow(r dbobj.RowScanner) error {
+	return r.Scan(o.MemberPointers()...)
+}
+
+func (o *rowidStruct) Key() int64 {
+	return o.ID
+}
+
+func (o *rowidStruct) SetID(id int64) {
+	o.ID = id
+}
+
+func (o *rowidStruct) SQLGet(keys ...interface{}) string {
+	return "select rowid,note from notes where ;"
+}
+
+func (o *rowidStruct) TableName() string {
+	return "notes"
+}
+
+func (o *rowidStruct) SelectFields() string {
+	return "rowid,note"
+}
+
+func (o *rowidStruct) QualifiedSelectFields() string {
+	return "notes.rowid,notes.note"
+}
+
+func (o *rowidStruct) InsertFields() string {
+	return "rowid,note"
+}
+
+func (o *rowidStruct) KeyField() string {
+	return "rowid"
+}
+
+func (o *rowidStruct) KeyName() string {
+	return "ID"
+}
+
+func (o *rowidStruct) AutoIncrement() bool {
+	return true
+}
+
+func (o *rowidStruct) Names() []string {
+	return []string{"Note"}
+}
+
+func (o *rowidStruct) Columns() []string {
+	return []string{"rowid", "note"}
+}
+
+func (o *rowidStruct) SchemaQuery() string {
+	return "create table if not exists notes (note text)"
+}
+
+func (o *rowidStruct) TableInfo() dbobj.TableMeta {
+	return dbobj.TableMeta{
+		Table: "notes",
+		Key:   "rowid",
+		Fields: []dbobj.FieldMeta{
+			{GoName: "ID", SQLName: "rowid", GoType: "int64"},
+			{GoName: "Note", SQLName: "note", GoType: "string"},
+		},
+	}
+}
+
+func (o *rowidStruct) FieldMap() map[string]interface{} {
+	return map[string]interface{}{
+		"rowid": &o.ID,
+		"note":  &o.Note,
+	}
+}
+
+func (o *rowidStruct) Clone() *rowidStruct {
+	c := new(rowidStruct)
+	c.ID = o.ID
+	c.Note = o.Note
+	return c
+}
+
+func (o *rowidStruct) Equal(other *rowidStruct) bool {
+	if o == nil || other == nil {
+		return o == other
+	}
+	return o.ID == other.ID &&
+		o.Note == other.Note
+}
+
+func (o *rowidStruct) KeyFields() []string {
+	return []string{"rowid"}
+}
+
+func (o *rowidStruct) KeyValues() []interface{} {
+	return []interface{}{o.ID}
+}
+
+func (o *rowidStruct) UpdateFieldQuery(d dbobj.Dialect, table string, cols ...string) (string, []interface{}, error) {
+	query := "update " + d.Quote(table) + " set "
+	args := make([]interface{}, 0, len(cols)+1)
+	for i, col := range cols {
+		switch col {
+		case "note":
+			args = append(args, o.Note)
+		default:
+			return "", nil, dbobj.UnknownColumn(table, col)
+		}
+		if i > 0 {
+			query += ","
+		}
+		query += d.Quote(col) + "=" + d.Placeholder(len(args))
+	}
+	return query + " where " + d.Quote("rowid") + "=" + d.Placeholder(len(args)+1), append(args, o.ID), nil
+}
+
+func (o *rowidStruct) String() string {
+	return dbobj.FormatObject("notes", "rowid,note", o.ID, o.Note)
+}
+
+func (o *rowidStruct) MarshalJSON() ([]byte, error) {
+	return dbobj.MarshalColumns(o.Columns(), o.ID, o.Note)
+}
+
+func (o *rowidStruct) UnmarshalJSON(data []byte) error {
+	return dbobj.UnmarshalColumns(data, map[string]interface{}{
+		"rowid": &o.ID,
+		"note":  &o.Note,
+	})
+}
+
+func (o *rowidStruct) ModifiedBy(user int64, t time.Time) {
+}
+
+// groupStruct DBObject generator
+var _ dbobj.DBObject = (*groupStruct)(nil)
+
+func (o *groupStruct) NewObj() interface{} {
+	return new(groupStruct)
+}
+
+// groupStruct DBObject interface functions
+func (o *groupStruct) InsertValues() []interface{} {
+	return []interface{}{o.Order, o.Select}
+}
+func (o *groupStruct) UpdateValues() []interface{} {
+	return []interface{}{o.Order, o.Select, o.ID}
+}
+
+func (o *groupStruct) MemberPointers() []interface{} {
+	return []interface{}{&o.ID, &o.Order, &o.Select}
+}
+
+func (o *groupStruct) FromRow(r dbobj.RowScanner) error {
+	return r.Scan(o.MemberPointers()...)
+}
+
+func (o *groupStruct) Key() int64 {
+	return o.ID
+}
+
+func (o *groupStruct) SetID(id int64) {
+	o.ID = id
+}
+
+func (o *groupStruct) SQLGet(keys ...interface{}) string {
+	return "select id,\"order\",\"select\" from \"group\" where ;"
+}
+
+func (o *groupStruct) TableName() string {
+	return "group"
+}
+
+func (o *groupStruct) SelectFields() string {
+	return "id,order,select"
+}
+
+func (o *groupStruct) QualifiedSelectFields() string {
+	return "\"group\".id,\"group\".\"order\",\"group\".\"select\""
+}
+
+func (o *groupStruct) InsertFields() string {
+	return "id,order,select"
+}
+
+func (o *groupStruct) KeyField() string {
+	return "id"
+}
+
+func (o *groupStruct) KeyName() string {
+	return "ID"
+}
+
+func (o *groupStruct) AutoIncrement() bool {
+	return true
+}
+
+func (o *groupStruct) Names() []string {
+	return []string{"Order", "Select"}
+}
+
+func (o *groupStruct) Columns() []string {
+	return []string{"id", "order", "select"}
+}
+
+func (o *groupStruct) SchemaQuery() string {
+	return "create table if not exists \"group\" (id integer not null primary key, \"order\" integer, \"select\" text, unique (\"select\"))"
+}
+
+func (o *groupStruct) TableInfo() dbobj.TableMeta {
+	return dbobj.TableMeta{
+		Table: "group",
+		Key:   "id",
+		Fields: []dbobj.FieldMeta{
+			{GoName: "ID", SQLName: "id", GoType: "int64"},
+			{GoName: "Order", SQLName: "order", GoType: "int"},
+			{GoName: "Select", SQLName: "select", GoType: "string"},
+		},
+	}
+}
+
+func (o *groupStruct) FieldMap() map[string]interface{} {
+	return map[string]interface{}{
+		"id":     &o.ID,
+		"order":  &o.Order,
+		"select": &o.Select,
+	}
+}
+
+func (o *groupStruct) Clone() *groupStruct {
+	c := new(groupStruct)
+	c.ID = o.ID
+	c.Order = o.Order
+	c.Select = o.Select
+	return c
+}
+
+func (o *groupStruct) Equal(other *groupStruct) bool {
+	if o == nil || other == nil {
+		return o == other
+	}
+	return o.ID == other.ID &&
+		o.Order == other.Order &&
+		o.Select == other.Select
+}
+
+func (o *groupStruct) KeyFields() []string {
+	return []string{"id"}
+}
+
+func (o *groupStruct) KeyValues() []interface{} {
+	return []interface{}{o.ID}
+}
+
+func (o *groupStruct) UpdateFieldQuery(d dbobj.Dialect, table string, cols ...string) (string, []interface{}, error) {
+	query := "update " + d.Quote(table) + " set "
+	args := make([]interface{}, 0, len(cols)+1)
+	for i, col := range cols {
+		switch col {
+		case "order":
+			args = append(args, o.Order)
+		case "select":
+			args = append(args, o.Select)
+		default:
+			return "", nil, dbobj.UnknownColumn(table, col)
+		}
+		if i > 0 {
+			query += ","
+		}
+		query += d.Quote(col) + "=" + d.Placeholder(len(args))
+	}
+	return query + " where " + d.Quote("id") + "=" + d.Placeholder(len(args)+1), append(args, o.ID), nil
+}
+
+func (o *groupStruct) UpsertByUnique(d dbobj.Dialect, table, name string) (string, []interface{}) {
+	insert := "insert into " + d.Quote(table) + " (" + d.Quote("order") + "," + d.Quote("select") + ") values (" + d.Placeholders(1, 2) + ")"
+	args := []interface{}{o.Order, o.Select}
+	switch name {
+	case "select":
+		if d == dbobj.MySQL {
+			return insert + " on duplicate key update " + d.Quote("order") + "=values(" + d.Quote("order") + ")", args
+		}
+		return insert + " on conflict (" + d.Quote("select") + ") do update set " + d.Quote("order") + "=excluded." + d.Quote("order"), args
+	}
+	return "", nil
+}
+
+func (o *groupStruct) UniqueFields(name string) []string {
+	switch name {
+	case "select":
+		return []string{"select"}
+	}
+	return nil
+}
+
+func (o *groupStruct) String() string {
+	return dbobj.FormatObject("group", "id,order,select", o.ID, o.Order, o.Select)
+}
+
+func (o *groupStruct) MarshalJSON() ([]byte, error) {
+	return dbobj.MarshalColumns(o.Columns(), o.ID, o.Order, o.Select)
+}
+
+func (o *groupStruct) UnmarshalJSON(data []byte) error {
+	return dbobj.UnmarshalColumns(data, map[string]interface{}{
+		"id":     &o.ID,
+		"order":  &o.Order,
+		"select": &o.Select,
+	})
+}
+
+func (o *groupStruct) ModifiedBy(user int64, t time.Time) {
+}
+
+// AllObjects holds a constructor for each generated type,
+// for operations across the schema such as creating all tables
+var AllObjects = []func() dbobj.DBObject{
+	func() dbobj.DBObject { return new(testStruct) },
+	func() dbobj.DBObject { return new(auditStruct) },
+	func() dbobj.DBObject { return new(listStruct) },
+	func() dbobj.DBObject { return new(stampStruct) },
+	func() dbobj.DBObject { return new(userStruct) },
+	func() dbobj.DBObject { return new(rowidStruct) },
+	func() dbobj.DBObject { return new(groupStruct) },
+}
@@ -36,6 +36,62 @@
 //
 //	//go:generate dbgen
 //
+// A []string field tagged split:"," is stored in a text column joined by the
+// delimiter, and split when scanned; an empty column is an empty slice.
+//
+// A field tagged omitempty:"true" is left out of inserts when it holds its zero
+// value, so the column's DEFAULT applies. Without a default the column is NULL,
+// which a NOT NULL column rejects, so such columns need a default.
+//
+// Blob columns should be declared as []byte, which round trip arbitrary bytes.
+// A string field tagged blob:"true" is stored as bytes via dbobj.Blob, which
+// avoids the driver storing it as text, but []byte is recommended for binary data.
+//
+// Further fields tagged key:"true" form a composite key with the first, listed
+// by the generated KeyFields and KeyValues methods so deletes match every key
+// column. Composite keys are usually tagged autoincrement:"false" as well.
+// A string key is supplied by the application, and its Key method returns 0.
+//
+// A field tagged rowid:"true", instead of key:"true", is keyed by the implicit
+// rowid of a SQLite table without an integer primary key, so KeyField returns
+// "rowid" and Add sets it to the last insert id. It needs no sql tag, and a
+// type with a rowid can't also have a key field.
+//
+// The generated SchemaQuery returns a create table statement for the type, where
+// a field tagged default:"..." sets the column default, e.g., default:"CURRENT_TIMESTAMP"
+// or default:"0". Defaults other than numbers, keywords and parenthesized expressions are quoted.
+//
+// Fields tagged audit:"user" and audit:"time", or update_user and update_time,
+// are set by the generated ModifiedBy method, which DBU.SaveAs calls. Fields tagged
+// audit:"create_user" and audit:"create_time" are set by a generated CreatedBy
+// method, which DBU.AddAs calls.
+//
+// Fields tagged unique:"name" form the named unique constraint, e.g., a natural
+// key alongside an autoincremented id, which is declared by SchemaQuery.
+// The generated UpsertByUnique returns the insert into the given table updating
+// the conflicting record, and UniqueFields its columns, for use by DBU.UpsertBy.
+//
+// A field tagged sensitive:"true", e.g., a password hash or token, is listed by
+// the generated Sensitive method so its values are logged as *** by DBU.
+//
+// Generation fails if several names share a sql tagged declaration, which must be
+// declared separately, if a sql tagged field isn't otherwise mapped, or if a
+// hand-written Names method of the type in the same file lists other fields than
+// those tagged.
+//
+// The -json flag adds MarshalJSON and UnmarshalJSON methods on the pointer type
+// that key the JSON object by sql column names, in select order, with times in RFC 3339.
+//
+// The -pkg flag generates into another package, e.g., a sibling gen package,
+// by wrapping each type in a type of the same name embedding the source type,
+// since methods can only be declared in the package of their type. The wrappers
+// implement DBObject, converting with gen.User{User: u} and w.User, and the source
+// package is imported by its module path. The types and their sql fields must be
+// exported, and enum types generated in the source package.
+//
+// The -buildtags flag adds a build constraint to the generated file, e.g.,
+// -buildtags sqlite so the generated code only compiles with the sqlite tag.
+//
 // The -type flag accepts a comma-separated list of types so a single run can
 // generate methods for multiple types. The default output file is db_generated.go,
 // where t is the lower-cased name of the first type listed. It can be overridden
@@ -49,29 +105,35 @@ import (
 	"fmt"
 	"go/ast"
 	"go/build"
+	buildconstraint "go/build/constraint"
 	"go/format"
 	"go/importer"
 	"go/parser"
+	"go/scanner"
 	"go/token"
 	"go/types"
 	"io/ioutil"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 )
 
 // For testing
-//go:generate ./dbgen -output generated_test.go -type testStruct struct_test.go
+//go:generate ./dbgen -stringer -registry -json -output generated_test.go -type testStruct,auditStruct,listStruct,stampStruct,userStruct,rowidStruct,groupStruct struct_test.go
 var (
 	typeNames  = flag.String("type", "", "comma-separated list of type names; leave blank for all")
 	outputFile = flag.String("output", "db_generated.go", "output file name")
-)
-
-const (
-	ignore = "github.com/paulstuart/dbobj.DBObject"
+	strict     = flag.Bool("strict", false, "fail if a type has no key field")
+	stringer   = flag.Bool("stringer", false, "generate a String method listing column values")
+	registry   = flag.Bool("registry", false, "generate an AllObjects registry of constructors for the generated types")
+	jsonFlag   = flag.Bool("json", false, "generate MarshalJSON and UnmarshalJSON methods keyed by sql column names")
+	buildTags  = flag.String("buildtags", "", "build constraint of the generated file, e.g., sqlite")
+	pkgName    = flag.String("pkg", "", "package of the generated file; another package than the source gets wrapper types")
 )
 
 // Usage is a replacement usage function for the flags package.
@@ -104,11 +166,37 @@ type SQLInfo struct {
 	Table     string            // sql table
 	KeyName   string            // member name for key
 	KeyField  string            // sql field for key
+	KeyPos    int               // position of the key among the declared fields
+	Keys      []string          // members of any further key fields, for composite keys
 	UserField string            // sql field for user id
 	TimeField string            // sql field for timestamp
+	CreatedBy string            // sql field for the creating user id
+	CreatedAt string            // sql field for the creation timestamp
 	Order     []string          // sql fields in order
 	Fields    map[string]string //
 	NoUpdate  map[string]struct{}
+	Natural   bool              // key is supplied by the application, not autoincremented
+	RowID     bool              // key is the implicit sqlite rowid
+	Tagged    int               // number of sql tagged fields, which must all be mapped
+	Err       error             // the first invalid tag, reported rather than generating the type
+	Wrap      map[string]string // member name to converter type
+	Types     map[string]string // member name to Go type
+	Enums     map[string]string // member name to enum type
+	Splits    map[string]string // member name to delimiter of []string members
+	OmitEmpty map[string]bool   // members left out of inserts when zero valued
+	Defaults  map[string]string // member name to column default
+	Sensitive []string          // members whose values must not be logged
+	Checks    map[string]constraint
+
+	Uniques []string            // names of unique constraints, in declared order
+	Unique  map[string][]string // unique constraint name to its members
+}
+
+// constraint holds the validation tags of a member
+type constraint struct {
+	NotNull bool
+	MaxLen  string
+	Min     string
 }
 
 func debugf(msg string, args ...interface{}) {
@@ -134,39 +222,54 @@ func main() {
 		dir string
 		g   Generator
 	)
-	if len(args) == 1 && isDirectory(args[0]) {
+	if len(args) == 1 && isDir(args[0]) {
 		debugf("parsing dir: %s\n", args[0])
-		g.parsePackageDir(args[0])
+		if err := g.parsePackageDir(args[0]); err != nil {
+			log.Fatal(err)
+		}
 	} else {
 		debugf("parsing file: %s\n", args[0])
 		dir = filepath.Dir(args[0])
-		g.parsePackageFiles(args)
+		if err := g.parsePackageFiles(args); err != nil {
+			log.Fatal(err)
+		}
 	}
 
-	// Print the header and package clause.
-	g.Printf("// generated by 'dbgen %s'; DO NOT EDIT\n", strings.Join(os.Args[1:], " "))
-	g.Printf("\npackage %s\n", g.pkg.name)
-	// TODO: conditionally add time if used
-	g.Printf(`
-
-import (
-	"time"
-)
-
-// in case time isn't otherwise referenced
-var _ = time.Now()
+	if _, err := buildConstraint(*buildTags); err != nil {
+		log.Fatal(err)
+	}
+	if *pkgName != "" && *pkgName != g.pkg.name {
+		source := dir
+		if source == "" {
+			source = args[0]
+		}
+		path, err := importPath(source)
+		if err != nil {
+			log.Fatal(err)
+		}
+		g.target, g.source = *pkgName, path
+	}
 
-`)
+	// Print the header and package clause.
+	g.header(strings.Join(os.Args[1:], " "))
 	if len(names) == 0 {
-		g.generate("")
+		if err := g.generate(""); err != nil {
+			log.Fatal(err)
+		}
 	} else {
 		for _, typeName := range names {
-			g.generate(typeName)
+			if err := g.generate(typeName); err != nil {
+				log.Fatal(err)
+			}
 		}
 	}
 
+	if *registry {
+		g.buildRegistry()
+	}
+
 	// Format the output.
-	src := g.format()
+	src, ferr := g.format()
 
 	// Write to file.
 	outputName := *outputFile
@@ -174,27 +277,129 @@ var _ = time.Now()
 		baseName := "db_generated.go"
 		outputName = filepath.Join(dir, strings.ToLower(baseName))
 	}
+	if ferr != nil {
+		// don't clobber the target with invalid Go, keep it for inspection
+		outputName += ".broken"
+	}
 	err := ioutil.WriteFile(outputName, src, 0644)
 	if err != nil {
 		log.Fatalf("writing output: %s", err)
 	}
+	if ferr != nil {
+		log.Fatalf("%s\nunformatted output written to %s", ferr, outputName)
+	}
+}
+
+// header prints the generated file header, build constraint, package clause, and imports.
+func (g *Generator) header(command string) {
+	g.Printf("// generated by 'dbgen %s'; DO NOT EDIT\n", command)
+	// the tags are validated by main
+	if lines, _ := buildConstraint(*buildTags); lines != "" {
+		// blank lines around the constraint keep it apart from the header and package doc
+		g.Printf("\n%s", lines)
+	}
+	if g.target == "" {
+		g.Printf("\npackage %s\n", g.pkg.name)
+	} else {
+		g.Printf("\npackage %s\n", g.target)
+	}
+	source := ""
+	if g.target != "" {
+		source = strconv.Quote(g.source)
+		if path.Base(g.source) != g.pkg.name {
+			source = g.pkg.name + " " + source
+		}
+		source = "\n\t" + source
+	}
+	// TODO: conditionally add time if used
+	g.Printf(`
+
+import (
+	"time"
+
+	"github.com/paulstuart/dbobj"%s
+)
+
+// in case time isn't otherwise referenced
+var _ = time.Now()
+
+`, source)
+}
+
+// buildConstraint returns the //go:build and // +build lines of the build tags expression,
+// or nothing if there are no tags
+func buildConstraint(tags string) (string, error) {
+	if strings.TrimSpace(tags) == "" {
+		return "", nil
+	}
+	expr, err := buildconstraint.Parse("//go:build " + tags)
+	if err != nil {
+		return "", fmt.Errorf("invalid build tags %q: %v", tags, err)
+	}
+	lines := []string{"//go:build " + expr.String()}
+	plus, err := buildconstraint.PlusBuildLines(expr)
+	if err != nil {
+		return "", fmt.Errorf("invalid build tags %q: %v", tags, err)
+	}
+	return strings.Join(append(lines, plus...), "\n") + "\n", nil
+}
+
+// importPath returns the import path of the package in the directory,
+// from the module path of the enclosing go.mod
+func importPath(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for root := abs; ; root = filepath.Dir(root) {
+		data, err := ioutil.ReadFile(filepath.Join(root, "go.mod"))
+		if err == nil {
+			m := modulePath.FindSubmatch(data)
+			if m == nil {
+				return "", fmt.Errorf("%s: no module path", filepath.Join(root, "go.mod"))
+			}
+			rel, err := filepath.Rel(root, abs)
+			if err != nil {
+				return "", err
+			}
+			return path.Join(string(m[1]), filepath.ToSlash(rel)), nil
+		}
+		if filepath.Dir(root) == root {
+			return "", fmt.Errorf("cannot find the import path of %s: no go.mod found", dir)
+		}
+	}
+}
+
+var modulePath = regexp.MustCompile(`(?m)^module\s+"?([^"\s]+)"?`)
+
+// isDir is the CLI wrapper around isDirectory, exiting on error.
+func isDir(name string) bool {
+	ok, err := isDirectory(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return ok
 }
 
 // isDirectory reports whether the named file is a directory.
-func isDirectory(name string) bool {
+func isDirectory(name string) (bool, error) {
 	info, err := os.Stat(name)
 	if err != nil {
-		log.Fatal(err)
+		return false, err
 	}
-	return info.IsDir()
+	return info.IsDir(), nil
 }
 
 // Generator holds the state of the analysis. Primarily used to buffer
 // the output for format.Source.
 // sql tag added for testing
 type Generator struct {
-	buf bytes.Buffer `sql:"buf" table:"generator"` // Accumulated output.
-	pkg *Package     // Package we are scanning.
+	buf       bytes.Buffer    `sql:"buf" table:"generator"` // Accumulated output.
+	pkg       *Package        // Package we are scanning.
+	enums     map[string]bool // enum types already generated
+	generated []string        // names of the types generated
+	target    string          // package of the generated file, if not the source package
+	source    string          // import path of the source package, if generating into target
 }
 
 func (g *Generator) Printf(format string, args ...interface{}) {
@@ -218,13 +423,14 @@ type Package struct {
 	defs     map[*ast.Ident]types.Object
 	files    []*File
 	typesPkg *types.Package
+	structs  map[string]*ast.StructType // struct types declared across all files
 }
 
 // parsePackageDir parses the package residing in the directory.
-func (g *Generator) parsePackageDir(directory string) {
+func (g *Generator) parsePackageDir(directory string) error {
 	pkg, err := build.Default.ImportDir(directory, 0)
 	if err != nil {
-		log.Fatalf("cannot process directory %s: %s", directory, err)
+		return fmt.Errorf("cannot process directory %s: %s", directory, err)
 	}
 	var names []string
 	for _, file := range pkg.GoFiles {
@@ -238,12 +444,12 @@ func (g *Generator) parsePackageDir(directory string) {
 	// names = append(names, pkg.TestGoFiles...) // These are also in the "foo" package.
 	names = append(names, pkg.SFiles...)
 	names = prefixDirectory(directory, names)
-	g.parsePackage(directory, names, nil)
+	return g.parsePackage(directory, names, nil)
 }
 
 // parsePackageFiles parses the package occupying the named files.
-func (g *Generator) parsePackageFiles(names []string) {
-	g.parsePackage(".", names, nil)
+func (g *Generator) parsePackageFiles(names []string) error {
+	return g.parsePackage(".", names, nil)
 }
 
 // prefixDirectory places the directory name on the beginning of each name in the list.
@@ -260,8 +466,8 @@ func prefixDirectory(directory string, names []string) []string {
 
 // parsePackage analyzes the single package constructed from the named files.
 // If text is non-nil, it is a string to be used instead of the content of the file,
-// to be used for testing. parsePackage exits if there is an error.
-func (g *Generator) parsePackage(directory string, names []string, text interface{}) {
+// to be used for testing. parsePackage returns an error if the files cannot be parsed.
+func (g *Generator) parsePackage(directory string, names []string, text interface{}) error {
 	var files []*File
 	var astFiles []*ast.File
 	g.pkg = new(Package)
@@ -272,7 +478,7 @@ func (g *Generator) parsePackage(directory string, names []string, text interfac
 		}
 		parsedFile, err := parser.ParseFile(fs, name, text, 0)
 		if err != nil && name != "db_generated.go" {
-			log.Fatalf("parsing package: %s: %s", name, err)
+			return fmt.Errorf("parsing package: %s: %s", name, err)
 		}
 		astFiles = append(astFiles, parsedFile)
 		files = append(files, &File{
@@ -281,77 +487,192 @@ func (g *Generator) parsePackage(directory string, names []string, text interfac
 		})
 	}
 	if len(astFiles) == 0 {
-		log.Fatalf("%s: no buildable Go files", directory)
+		return fmt.Errorf("%s: no buildable Go files", directory)
 	}
 	g.pkg.name = astFiles[0].Name.Name
 	g.pkg.files = files
 	g.pkg.dir = directory
+	g.pkg.structs = structTypes(astFiles)
 	// Type check the package.
-	g.pkg.check(fs, astFiles)
+	return g.pkg.check(fs, astFiles)
 }
 
-// check type-checks the package. The package must be OK to proceed.
-func (pkg *Package) check(fs *token.FileSet, astFiles []*ast.File) {
+// structTypes returns the struct type declarations of all the files, by type name,
+// so that embedded types can be resolved regardless of the file they are declared in.
+func structTypes(files []*ast.File) map[string]*ast.StructType {
+	structs := make(map[string]*ast.StructType)
+	for _, file := range files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			if spec, ok := node.(*ast.TypeSpec); ok {
+				if st, ok := spec.Type.(*ast.StructType); ok {
+					structs[spec.Name.Name] = st
+				}
+			}
+			return true
+		})
+	}
+	return structs
+}
+
+// generatedMethods are the methods dbgen declares, which the source may use
+// before they are generated
+var generatedMethods = map[string]bool{
+	"AutoIncrement": true, "Clone": true, "Columns": true, "CreatedBy": true,
+	"DeleteQuery": true, "Equal": true, "FieldMap": true, "FromRow": true,
+	"InsertFields": true, "InsertQuery": true, "InsertValues": true, "Key": true,
+	"KeyField": true, "KeyFields": true, "KeyName": true, "KeyValues": true,
+	"MarshalJSON": true, "MemberPointers": true, "ModifiedBy": true, "Names": true,
+	"NewObj": true, "QualifiedSelectFields": true, "ReplaceQuery": true, "SQLGet": true,
+	"Scan": true, "SchemaQuery": true, "SelectFields": true, "Sensitive": true,
+	"SetID": true, "String": true, "TableInfo": true, "TableName": true,
+	"UniqueFields": true, "UnmarshalJSON": true, "UpdateFieldQuery": true, "UpdateQuery": true,
+	"UpdateValues": true, "UpsertByUnique": true, "Validate": true, "Value": true,
+}
+
+var (
+	missingMethod = regexp.MustCompile(`(?:has no field or method|missing method) (\w+)`)
+	undefinedName = regexp.MustCompile(`^undefined: (\w+)$`)
+)
+
+// generatedDecls returns the package level names dbgen may declare:
+// the enum types named by struct tags, and the registry
+func generatedDecls(files []*ast.File) map[string]bool {
+	decls := map[string]bool{"AllObjects": true}
+	for _, file := range files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			if field, ok := node.(*ast.Field); ok && field.Tag != nil {
+				if tag, err := strconv.Unquote(field.Tag.Value); err == nil {
+					if enum := reflect.StructTag(tag).Get("enum"); len(enum) > 0 {
+						decls[enum] = true
+					}
+				}
+			}
+			return true
+		})
+	}
+	return decls
+}
+
+// check type-checks the package. The only errors tolerated are those due to
+// the methods and declarations being generated, which don't exist yet
+func (pkg *Package) check(fs *token.FileSet, astFiles []*ast.File) error {
 	pkg.defs = make(map[*ast.Ident]types.Object)
+	decls := generatedDecls(astFiles)
+	var unexpected error
 	config := types.Config{
 		Importer:    importer.Default(),
 		FakeImportC: true,
+		// Setting Error keeps the check going past the expected errors
 		Error: func(e error) {
-			//fmt.Println("PKG ERR:", e)
-			err := e.(types.Error)
-			i := strings.Index(err.Msg, "DBObject")
-			if strings.HasSuffix(err.Msg, ignore) || i > 0 {
-				err.Msg = ""
-				//e = nil
+			msg := e.Error()
+			if err, ok := e.(types.Error); ok {
+				msg = err.Msg
+			}
+			if m := missingMethod.FindStringSubmatch(msg); m != nil && generatedMethods[m[1]] {
 				return
 			}
-			if strings.Index(err.Msg, "has no field or method") > 0 {
-				switch {
-				case strings.Index(err.Msg, "TableName") > 0:
-				default:
-					file := err.Fset.File(err.Pos)
-					log.Println("POS:", err.Pos, "MSG:", err.Msg, "INDEX:", i, "SOFT:", err.Soft, "FILE:", file.Name())
-					return
-				}
-				err.Msg = ""
-				//e = nil
+			if m := undefinedName.FindStringSubmatch(msg); m != nil && decls[m[1]] {
+				return
+			}
+			if unexpected == nil {
+				unexpected = e
 			}
 		},
 	}
 	info := &types.Info{
 		Defs: pkg.defs,
 	}
-	typesPkg, err := config.Check(pkg.dir, fs, astFiles, info)
-	if err != nil {
-		log.Println("failed checking package:", err)
+	pkg.typesPkg, _ = config.Check(pkg.dir, fs, astFiles, info)
+	if unexpected != nil {
+		return fmt.Errorf("type checking package: %s", unexpected)
 	}
-	pkg.typesPkg = typesPkg
+	return nil
 }
 
 // generate produces the DBObject methods for the named type.
-func (g *Generator) generate(typeName string) {
+func (g *Generator) generate(typeName string) error {
+	if g.pkg == nil {
+		return fmt.Errorf("no package parsed for type: %s", typeName)
+	}
+	var values []*SQLInfo
 	for _, file := range g.pkg.files {
 		file.findName = typeName
+		file.values = nil
 		if file.file != nil {
 			ast.Inspect(file.file, file.genDecl)
 			for _, v := range file.values {
-				g.buildWrappers(v)
+				if v.Err != nil {
+					return v.Err
+				}
+				if err := checkNames(file.file, v); err != nil {
+					return err
+				}
+			}
+			values = append(values, file.values...)
+		}
+	}
+	for _, v := range values {
+		if n := len(v.members()); n != v.Tagged {
+			return fmt.Errorf("type %s has %d sql tagged fields but %d are mapped", v.Name, v.Tagged, n)
+		}
+		if len(v.KeyField) == 0 {
+			if *strict {
+				return fmt.Errorf("type %s (table %s) has no key field", v.Name, v.Table)
+			}
+			log.Printf("warning: type %s (table %s) has no key field; updates and deletes will not work", v.Name, v.Table)
+		}
+		if g.target != "" {
+			if err := v.wrappable(g.pkg.name); err != nil {
+				return err
 			}
+			g.Printf(stringWrapper, v.Name, g.pkg.name)
 		}
+		g.buildWrappers(v)
+		g.generated = append(g.generated, v.Name)
 	}
+	return nil
+}
+
+// buildRegistry generates the AllObjects constructors of the generated types
+func (g *Generator) buildRegistry() {
+	list := make([]string, len(g.generated))
+	for i, name := range g.generated {
+		list[i] = fmt.Sprintf("func() dbobj.DBObject { return new(%s) },", name)
+	}
+	g.Printf(stringRegistry, strings.Join(list, "\n"))
 }
 
 // format returns the gofmt-ed contents of the Generator's buffer.
-func (g *Generator) format() []byte {
+func (g *Generator) format() ([]byte, error) {
 	src, err := format.Source(g.buf.Bytes())
 	if err != nil {
 		// Should never happen, but can arise when developing this code.
-		// The user can compile the output to see the error.
-		log.Printf("warning: internal error: invalid Go generated: %s", err)
-		log.Printf("warning: compile the package to analyze the error")
-		return g.buf.Bytes()
+		return g.buf.Bytes(), fmt.Errorf("internal error: invalid Go generated: %s\n%s", err, snippet(g.buf.Bytes(), err))
 	}
-	return src
+	return src, nil
+}
+
+// snippet returns the generated lines surrounding the position of a format error
+func snippet(src []byte, err error) string {
+	list, ok := err.(scanner.ErrorList)
+	if !ok || len(list) == 0 {
+		return ""
+	}
+	const context = 3
+	line := list[0].Pos.Line
+	lines := strings.Split(string(src), "\n")
+	var b strings.Builder
+	for i := line - context; i <= line+context; i++ {
+		if i < 1 || i > len(lines) {
+			continue
+		}
+		marker := "  "
+		if i == line {
+			marker = "> "
+		}
+		fmt.Fprintf(&b, "%s%4d: %s\n", marker, i, lines[i-1])
+	}
+	return b.String()
 }
 
 //
@@ -359,46 +680,157 @@ func (g *Generator) format() []byte {
 // Parse the tags
 //
 //
-func sqlTags(typeName string, fields *ast.FieldList) *SQLInfo {
+func sqlTags(typeName string, fields *ast.FieldList, structs map[string]*ast.StructType) *SQLInfo {
+	list := flattenFields(fields, structs, map[string]bool{typeName: true})
 	info := SQLInfo{}
 	info.Fields = make(map[string]string) // [memberName]sqlName
-	info.Order = make([]string, 0, len(fields.List))
+	info.Order = make([]string, 0, len(list))
 	info.NoUpdate = make(map[string]struct{})
+	info.Wrap = make(map[string]string)
+	info.Types = make(map[string]string)
+	info.Enums = make(map[string]string)
+	info.Splits = make(map[string]string)
+	info.OmitEmpty = make(map[string]bool)
+	info.Defaults = make(map[string]string)
+	info.Checks = make(map[string]constraint)
 	good := false
-	for _, field := range fields.List {
-		if t := field.Tag; t != nil {
-			s := string(t.Value)
-			// the code uses backticks to metaquote, need to strip them whilst evaluating
-			tag := reflect.StructTag(s[1 : len(s)-1])
-			if sql := tag.Get("sql"); len(sql) > 0 {
-				//fmt.Println("SQL:", sql)
-				if table := tag.Get("table"); len(table) > 0 {
-					info.Table = table
-				}
-				if key := tag.Get("key"); len(key) > 0 {
-					info.KeyName = string(field.Names[0].Name)
-					info.KeyField = sql
-				} else {
-					info.Fields[field.Names[0].Name] = sql
-					info.Order = append(info.Order, field.Names[0].Name)
-				}
-				good = true
-			}
-			// TODO: rething 'audit' feature
-			if audit := tag.Get("audit"); len(audit) > 0 {
-				switch {
-				case audit == "user":
-					info.UserField = string(field.Names[0].Name)
-				case audit == "time":
-					info.TimeField = string(field.Names[0].Name)
+	for _, field := range list {
+		// embedded fields that weren't flattened and untagged fields aren't persisted
+		if len(field.Names) == 0 || field.Tag == nil {
+			continue
+		}
+		s := string(field.Tag.Value)
+		// the code uses backticks to metaquote, need to strip them whilst evaluating
+		tag := reflect.StructTag(s[1 : len(s)-1])
+		sql := tag.Get("sql")
+		rowid, _ := strconv.ParseBool(tag.Get("rowid"))
+		if rowid {
+			// the implicit key of tables without an integer primary key
+			sql = "rowid"
+		}
+		if len(sql) == 0 {
+			continue
+		}
+		info.Tagged += len(field.Names)
+		name := field.Names[0].Name
+		key := tag.Get("key")
+		if rowid && len(key) > 0 || rowid && len(info.KeyName) > 0 || len(key) > 0 && info.RowID {
+			info.invalid(fmt.Errorf("type %s field %s: rowid and key are mutually exclusive", typeName, name))
+			continue
+		}
+		if len(field.Names) > 1 {
+			info.invalid(fmt.Errorf("type %s fields %s and %s share the tag sql:%q; declare them separately", typeName, name, field.Names[1].Name, sql))
+			continue
+		}
+		info.Types[name] = types.ExprString(field.Type)
+		if table := tag.Get("table"); len(table) > 0 {
+			info.Table = table
+		}
+		if rowid {
+			info.KeyName = name
+			info.KeyField = sql
+			info.KeyPos = len(info.Order)
+			info.RowID = true
+		} else if len(key) > 0 && len(info.KeyName) > 0 {
+			// further key fields are stored as regular fields
+			info.Keys = append(info.Keys, name)
+			info.Fields[name] = sql
+			info.Order = append(info.Order, name)
+		} else if len(key) > 0 {
+			info.KeyName = name
+			info.KeyField = sql
+			info.KeyPos = len(info.Order)
+			if auto := tag.Get("autoincrement"); len(auto) > 0 {
+				if ok, err := strconv.ParseBool(auto); err == nil && !ok {
+					info.Natural = true
 				}
 			}
-			if update := tag.Get("update"); len(update) > 0 {
-				if up, err := strconv.ParseBool(update); err == nil && !up {
-					//if _, err := strconv.ParseBool(update); err == nil {
-					//fmt.Println("NO UPDATE:", field.Names[0].Name)
-					info.NoUpdate[field.Names[0].Name] = struct{}{}
-				}
+			if info.Types[name] == "string" {
+				// text keys are supplied by the application
+				info.Natural = true
+			}
+		} else {
+			info.Fields[name] = sql
+			info.Order = append(info.Order, name)
+		}
+		good = true
+		// TODO: rething 'audit' feature
+		if audit := tag.Get("audit"); len(audit) > 0 {
+			switch audit {
+			case "user", "update_user":
+				info.UserField = name
+			case "time", "update_time":
+				info.TimeField = name
+			case "create_user":
+				info.CreatedBy = name
+			case "create_time":
+				info.CreatedAt = name
+			default:
+				log.Printf("warning: type %s field %s has unknown audit tag %q", typeName, name, audit)
+			}
+		}
+		if timefmt := tag.Get("timefmt"); timefmt == "unix" {
+			info.Wrap[name] = "dbobj.UnixTime"
+		} else if info.Types[name] == "time.Time" {
+			// tolerates NULL and datetimes stored as text
+			info.Wrap[name] = "dbobj.NullableTime"
+		}
+		if enum := tag.Get("enum"); len(enum) > 0 {
+			info.Enums[name] = enum
+			// fields not declared as the enum type are converted
+			if types.ExprString(field.Type) != enum {
+				info.Wrap[name] = enum
+			}
+		}
+		if blob, _ := strconv.ParseBool(tag.Get("blob")); blob {
+			switch info.Types[name] {
+			case "[]byte":
+				// stored as is
+			case "string":
+				log.Printf("note: type %s field %s is a string blob; []byte is recommended for binary data", typeName, name)
+				info.Wrap[name] = "dbobj.Blob"
+			default:
+				log.Printf("warning: type %s field %s is tagged blob but is a %s", typeName, name, info.Types[name])
+			}
+		}
+		if sep, ok := tag.Lookup("split"); ok {
+			if info.Types[name] == "[]string" && len(sep) > 0 {
+				info.Splits[name] = sep
+			} else {
+				log.Printf("warning: type %s field %s split tag requires a []string and a delimiter", typeName, name)
+			}
+		}
+		if omit, _ := strconv.ParseBool(tag.Get("omitempty")); omit {
+			info.OmitEmpty[name] = true
+		}
+		if unique := tag.Get("unique"); len(unique) > 0 {
+			if info.Unique == nil {
+				info.Unique = make(map[string][]string)
+			}
+			if _, ok := info.Unique[unique]; !ok {
+				info.Uniques = append(info.Uniques, unique)
+			}
+			info.Unique[unique] = append(info.Unique[unique], name)
+		}
+		if secret, _ := strconv.ParseBool(tag.Get("sensitive")); secret {
+			info.Sensitive = append(info.Sensitive, name)
+		}
+		if def, ok := tag.Lookup("default"); ok {
+			info.Defaults[name] = def
+		}
+		check := constraint{
+			MaxLen: tag.Get("maxlen"),
+			Min:    tag.Get("min"),
+		}
+		if notnull := tag.Get("notnull"); len(notnull) > 0 {
+			check.NotNull, _ = strconv.ParseBool(notnull)
+		}
+		if check != (constraint{}) {
+			info.Checks[name] = check
+		}
+		if update := tag.Get("update"); len(update) > 0 {
+			if up, err := strconv.ParseBool(update); err == nil && !up {
+				info.NoUpdate[name] = struct{}{}
 			}
 		}
 	}
@@ -408,14 +840,113 @@ func sqlTags(typeName string, fields *ast.FieldList) *SQLInfo {
 	return nil
 }
 
+// flattenFields returns the fields of the list, with the fields of embedded
+// struct types declared in the package promoted in place of the embedded field.
+func flattenFields(fields *ast.FieldList, structs map[string]*ast.StructType, seen map[string]bool) []*ast.Field {
+	list := make([]*ast.Field, 0, len(fields.List))
+	for _, field := range fields.List {
+		if len(field.Names) == 0 {
+			expr := field.Type
+			if star, ok := expr.(*ast.StarExpr); ok {
+				expr = star.X
+			}
+			if ident, ok := expr.(*ast.Ident); ok && !seen[ident.Name] {
+				if st, ok := structs[ident.Name]; ok {
+					seen[ident.Name] = true
+					list = append(list, flattenFields(st.Fields, structs, seen)...)
+					continue
+				}
+			}
+		}
+		list = append(list, field)
+	}
+	return list
+}
+
+// checkNames returns an error if the file declares a Names method for the type
+// listing other members than the sql tagged fields, e.g., when a field was added
+// without a sql tag, or tagged without updating Names. The key may be listed or not
+func checkNames(f *ast.File, s *SQLInfo) error {
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Name.Name != "Names" || fn.Recv == nil || len(fn.Recv.List) != 1 || fn.Body == nil {
+			continue
+		}
+		recv := fn.Recv.List[0].Type
+		if star, ok := recv.(*ast.StarExpr); ok {
+			recv = star.X
+		}
+		if ident, ok := recv.(*ast.Ident); !ok || ident.Name != s.Name {
+			continue
+		}
+		names, ok := returnedStrings(fn.Body)
+		if !ok {
+			// not a list of literals, so it can't be checked
+			return nil
+		}
+		var listed, tagged []string
+		for _, name := range names {
+			if name != s.KeyName {
+				listed = append(listed, name)
+			}
+		}
+		for _, k := range s.members() {
+			if k != s.KeyName {
+				tagged = append(tagged, k)
+			}
+		}
+		if strings.Join(listed, ",") != strings.Join(tagged, ",") {
+			return fmt.Errorf("type %s Names lists %d fields %v but %d are sql tagged %v", s.Name, len(listed), listed, len(tagged), tagged)
+		}
+	}
+	return nil
+}
+
+// returnedStrings returns the strings of a function body returning a []string literal
+func returnedStrings(body *ast.BlockStmt) ([]string, bool) {
+	for _, stmt := range body.List {
+		ret, ok := stmt.(*ast.ReturnStmt)
+		if !ok || len(ret.Results) != 1 {
+			continue
+		}
+		lit, ok := ret.Results[0].(*ast.CompositeLit)
+		if !ok {
+			return nil, false
+		}
+		list := make([]string, 0, len(lit.Elts))
+		for _, elt := range lit.Elts {
+			basic, ok := elt.(*ast.BasicLit)
+			if !ok || basic.Kind != token.STRING {
+				return nil, false
+			}
+			v, err := strconv.Unquote(basic.Value)
+			if err != nil {
+				return nil, false
+			}
+			list = append(list, v)
+		}
+		return list, true
+	}
+	return nil, false
+}
+
 // genDecl processes one declaration clause.
 func (f *File) genDecl(node ast.Node) bool {
 	switch x := node.(type) {
 	case *ast.TypeSpec:
 		f.TypeName = x.Name.Name
+		// methods of a generic type need its type parameters, which dbgen doesn't emit
+		if x.TypeParams != nil && len(x.TypeParams.List) > 0 {
+			if st, ok := x.Type.(*ast.StructType); ok && (len(f.findName) == 0 || f.findName == f.TypeName) {
+				if sqlTags(f.TypeName, st.Fields, f.pkg.structs) != nil {
+					log.Printf("warning: skipping generic type %s; type parameters are not supported", f.TypeName)
+				}
+			}
+			return false
+		}
 	case *ast.StructType:
 		if len(f.findName) == 0 || f.findName == f.TypeName {
-			if tags := sqlTags(f.TypeName, x.Fields); tags != nil {
+			if tags := sqlTags(f.TypeName, x.Fields, f.pkg.structs); tags != nil {
 				tags.Name = f.TypeName
 				f.values = append(f.values, tags)
 			}
@@ -427,43 +958,43 @@ func (f *File) genDecl(node ast.Node) bool {
 
 // buildWrappers generates the variables and String method for a single run of contiguous values.
 func (g *Generator) buildWrappers(s *SQLInfo) {
-	names := []string{}
-	elem := []string{}
-	ptr := []string{}
-	//set := []string{}
-	sql := []string{}
-	//insert_fields := []string{}
-	if len(s.KeyField) > 0 {
-		sql = append(sql, s.KeyField)
-	}
-	if len(s.KeyName) > 0 {
-		ptr = append(ptr, "&o."+s.KeyName)
+	// select fields and member pointers come from the same list, so they can't diverge
+	sql, ptr := s.selected()
+	fields := make([]string, len(sql))
+	for i, col := range sql {
+		fields[i] = fmt.Sprintf("%q: %s", col, ptr[i])
 	}
-	for _, k := range s.Order {
-		if len(k) > 0 {
-			v := s.Fields[k]
-			sql = append(sql, v)
-			names = append(names, `"`+k+`"`)
-			elem = append(elem, "o."+k)
-			ptr = append(ptr, "&o."+k)
-			//set = append(set, v+"=?")
-			/*
-				if _, ok := s.NoUpdate[v]; !ok {
-					insert_fields = append(insert_fields, v)
-				}
-			*/
+	names := []string{}
+	elem := []string{}   // values excluding the key
+	insert := []string{} // values including the key if natural
+	for _, k := range s.members() {
+		if k == s.KeyName {
+			if s.Natural {
+				insert = append(insert, "o."+k)
+			}
+			continue
+		}
+		names = append(names, `"`+k+`"`)
+		elem = append(elem, s.value(k))
+		if s.OmitEmpty[k] {
+			insert = append(insert, fmt.Sprintf("dbobj.OmitEmpty(%s)", s.value(k)))
+		} else {
+			insert = append(insert, s.value(k))
 		}
 	}
+	g.buildEnums(s)
 	g.Printf("\n\n//\n// %s DBObject generator\n//\n", s.Name)
+	g.Printf(stringAssert, s.Name)
 	g.Printf(stringNewObj, s.Name)
 	g.Printf("\n//\n// %s DBObject interface functions\n//\n", s.Name)
-	g.Printf(stringInsertValues, s.Name, strings.Join(elem, ","))
+	g.Printf(stringInsertValues, s.Name, strings.Join(insert, ","))
 	if len(s.KeyName) > 0 {
 		elem = append(elem, "o."+s.KeyName)
 	}
 	g.Printf(stringUpdateValues, s.Name, strings.Join(elem, ","))
 	g.Printf(stringMemberPointers, s.Name, strings.Join(ptr, ","))
-	if len(s.KeyField) > 0 {
+	g.Printf(stringFromRow, s.Name)
+	if len(s.KeyField) > 0 && s.Types[s.KeyName] != "string" {
 		g.Printf(stringKey, s.Name, s.KeyName)
 		g.Printf(stringSetID, s.Name, s.KeyName)
 	} else {
@@ -471,14 +1002,456 @@ func (g *Generator) buildWrappers(s *SQLInfo) {
 		g.Printf(stringNoSetID, s.Name)
 	}
 
-	g.Printf(stringSQLGet, s.Name, s.Table, strings.Join(sql, ","), "")
+	g.Printf(stringSQLGet, s.Name, fmt.Sprintf("select %s from %s where %s;", strings.Join(quoteAll(sql), ","), quote(s.Table), ""))
 	g.Printf(stringTableName, s.Name, s.Table)
 	g.Printf(stringSelectFields, s.Name, strings.Join(sql, ","))
+	qualified := make([]string, len(sql))
+	for i, col := range sql {
+		qualified[i] = quote(s.Table) + "." + quote(col)
+	}
+	g.Printf(stringQualifiedSelectFields, s.Name, strings.Join(qualified, ","))
 	g.Printf(stringInsertFields, s.Name, strings.Join(sql, ","))
 	g.Printf(stringKeyField, s.Name, s.KeyField)
 	g.Printf(stringKeyName, s.Name, s.KeyName)
+	g.Printf(stringAutoIncrement, s.Name, !s.Natural)
 	g.Printf(stringNames, s.Name, strings.Join(names, ","))
-	g.Printf(auditString(s.Name, s.UserField, s.TimeField))
+	columns := make([]string, len(sql))
+	for i, col := range sql {
+		columns[i] = strconv.Quote(col)
+	}
+	g.Printf(stringColumns, s.Name, strings.Join(columns, ", "))
+	g.Printf(stringSchemaQuery, s.Name, s.schema())
+	meta := make([]string, 0, len(sql))
+	for _, k := range s.members() {
+		meta = append(meta, fmt.Sprintf("{GoName: %q, SQLName: %q, GoType: %q},", k, s.column(k), s.Types[k]))
+	}
+	g.Printf(stringTableInfo, s.Name, s.Table, s.KeyField, strings.Join(meta, "\n"))
+	g.Printf(stringFieldMap, s.Name, strings.Join(fields, ",\n"))
+	g.Printf(stringClone, s.Name, strings.Join(s.copies(), "\n"))
+	g.Printf(stringEqual, s.Name, strings.Join(s.compares(), " &&\n"))
+	if len(s.KeyField) > 0 {
+		fields := []string{strconv.Quote(s.KeyField)}
+		values := []string{"o." + s.KeyName}
+		for _, k := range s.Keys {
+			fields = append(fields, strconv.Quote(s.Fields[k]))
+			values = append(values, s.value(k))
+		}
+		g.Printf(stringKeyValues, s.Name, strings.Join(fields, ", "), strings.Join(values, ", "))
+	}
+	if len(s.KeyField) > 0 {
+		g.Printf(stringUpdateFieldQuery, s.Name, strings.Join(s.patches(), "\n"), s.KeyField, s.KeyName)
+	}
+	if len(s.Uniques) > 0 {
+		fields := make([]string, len(s.Uniques))
+		for i, name := range s.Uniques {
+			cols := make([]string, len(s.Unique[name]))
+			for j, k := range s.Unique[name] {
+				cols[j] = strconv.Quote(s.column(k))
+			}
+			fields[i] = fmt.Sprintf("case %q:\n\treturn []string{%s}", name, strings.Join(cols, ", "))
+		}
+		insert, args, cases := s.upserts()
+		g.Printf(stringUpsertByUnique, s.Name, insert, args, strings.Join(cases, "\n"), strings.Join(fields, "\n"))
+	}
+	if len(s.Sensitive) > 0 {
+		secrets := make([]string, len(s.Sensitive))
+		for i, k := range s.Sensitive {
+			secrets[i] = strconv.Quote(s.column(k))
+		}
+		g.Printf(stringSensitive, s.Name, strings.Join(secrets, ", "))
+	}
+	if *stringer {
+		values := make([]string, 0, len(sql))
+		for _, k := range s.members() {
+			if s.sensitive(k) {
+				values = append(values, `"***"`)
+				continue
+			}
+			values = append(values, "o."+k)
+		}
+		g.Printf(stringString, s.Name, s.Table, strings.Join(sql, ","), strings.Join(values, ", "))
+	}
+	if *jsonFlag {
+		values := make([]string, 0, len(sql))
+		pointers := make([]string, 0, len(sql))
+		for _, k := range s.members() {
+			values = append(values, "o."+k)
+			pointers = append(pointers, fmt.Sprintf("%q: &o.%s,", s.column(k), k))
+		}
+		g.Printf(stringJSON, s.Name, strings.Join(values, ", "), strings.Join(pointers, "\n"))
+	}
+	if checks := s.validations(); len(checks) > 0 {
+		g.Printf(stringValidate, s.Name, strings.Join(checks, "\n"))
+	}
+	g.Printf(auditString(s))
+}
+
+// upserts returns the insert of an upsert, its args, and the switch cases
+// returning the upsert on each unique constraint, by dialect
+func (s *SQLInfo) upserts() (insert, args string, cases []string) {
+	var cols, values []string
+	for _, k := range s.members() {
+		if k == s.KeyName && !s.Natural {
+			continue
+		}
+		cols = append(cols, s.column(k))
+		values = append(values, s.value(k))
+	}
+	insert = fmt.Sprintf(`"insert into " + d.Quote(table) + " (" + %s + ") values (" + d.Placeholders(1, %d) + ")"`, quoteExpr(cols, ","), len(cols))
+	cases = make([]string, 0, len(s.Uniques))
+	for _, name := range s.Uniques {
+		unique := make(map[string]bool)
+		conflict := make([]string, 0, len(s.Unique[name]))
+		for _, k := range s.Unique[name] {
+			unique[k] = true
+			conflict = append(conflict, s.column(k))
+		}
+		var set, dup []string
+		for _, k := range s.Order {
+			if _, ok := s.NoUpdate[k]; ok || unique[k] {
+				continue
+			}
+			set = append(set, fmt.Sprintf(`d.Quote(%[1]q) + "=excluded." + d.Quote(%[1]q)`, s.column(k)))
+			dup = append(dup, fmt.Sprintf(`d.Quote(%[1]q) + "=values(" + d.Quote(%[1]q) + ")"`, s.column(k)))
+		}
+		action := `") do nothing"`
+		// MySQL has no do nothing, so the conflicting column is set to itself
+		duplicate := fmt.Sprintf(`" on duplicate key update " + d.Quote(%[1]q) + "=" + d.Quote(%[1]q)`, conflict[0])
+		if len(set) > 0 {
+			action = `") do update set " + ` + strings.Join(set, ` + "," + `)
+			duplicate = `" on duplicate key update " + ` + strings.Join(dup, ` + "," + `)
+		}
+		conflicts := fmt.Sprintf(`" on conflict (" + %s + %s`, quoteExpr(conflict, ","), action)
+		cases = append(cases, fmt.Sprintf("case %q:\n\tif d == dbobj.MySQL {\n\treturn insert + %s, args\n}\nreturn insert + %s, args", name, duplicate, conflicts))
+	}
+	return insert, strings.Join(values, ", "), cases
+}
+
+// sensitive reports whether the member's value must not be logged
+func (s *SQLInfo) sensitive(k string) bool {
+	for _, name := range s.Sensitive {
+		if name == k {
+			return true
+		}
+	}
+	return false
+}
+
+// invalid records the first invalid tag of the type
+func (s *SQLInfo) invalid(err error) {
+	if s.Err == nil {
+		s.Err = err
+	}
+}
+
+// schema returns the create table statement for the declared columns
+func (s *SQLInfo) schema() string {
+	defs := make([]string, 0, len(s.Order)+2)
+	for _, k := range s.members() {
+		if k == s.KeyName && s.RowID {
+			// the rowid isn't declared
+			continue
+		}
+		if k == s.KeyName {
+			kind := "integer"
+			if s.Natural {
+				kind = s.sqlType(k)
+			}
+			if len(s.Keys) > 0 {
+				defs = append(defs, quote(s.KeyField)+" "+kind+" not null")
+			} else {
+				defs = append(defs, quote(s.KeyField)+" "+kind+" not null primary key")
+			}
+			continue
+		}
+		def := quote(s.Fields[k]) + " " + s.sqlType(k)
+		if s.Checks[k].NotNull {
+			def += " not null"
+		}
+		if v, ok := s.Defaults[k]; ok {
+			def += " default " + sqlDefault(v)
+		}
+		defs = append(defs, def)
+	}
+	if len(s.Keys) > 0 {
+		keys := []string{quote(s.KeyField)}
+		for _, k := range s.Keys {
+			keys = append(keys, quote(s.Fields[k]))
+		}
+		defs = append(defs, "primary key ("+strings.Join(keys, ", ")+")")
+	}
+	for _, name := range s.Uniques {
+		cols := make([]string, len(s.Unique[name]))
+		for i, k := range s.Unique[name] {
+			cols[i] = quote(s.column(k))
+		}
+		defs = append(defs, "unique ("+strings.Join(cols, ", ")+")")
+	}
+	return fmt.Sprintf("create table if not exists %s (%s)", quote(s.Table), strings.Join(defs, ", "))
+}
+
+// reserved holds the SQL keywords that dbobj's Dialect.Quote quotes when used as identifiers
+var reserved = map[string]bool{
+	"add": true, "all": true, "alter": true, "and": true, "as": true, "asc": true,
+	"between": true, "by": true, "case": true, "check": true, "column": true,
+	"constraint": true, "create": true, "cross": true, "default": true, "delete": true,
+	"desc": true, "distinct": true, "drop": true, "else": true, "end": true,
+	"exists": true, "foreign": true, "from": true, "full": true, "group": true,
+	"having": true, "in": true, "index": true, "inner": true, "insert": true,
+	"into": true, "is": true, "join": true, "key": true, "left": true, "like": true,
+	"limit": true, "not": true, "null": true, "offset": true, "on": true, "or": true,
+	"order": true, "outer": true, "primary": true, "references": true, "right": true,
+	"select": true, "set": true, "table": true, "then": true, "to": true,
+	"union": true, "unique": true, "update": true, "user": true, "using": true,
+	"values": true, "when": true, "where": true, "with": true,
+}
+
+// quote returns the identifier double quoted if it is a reserved word, for the
+// statements generated as constants, which can't depend on the dialect
+func quote(name string) string {
+	if !reserved[strings.ToLower(name)] {
+		return name
+	}
+	return `"` + name + `"`
+}
+
+// quoteAll returns the identifiers quoted by quote
+func quoteAll(names []string) []string {
+	list := make([]string, len(names))
+	for i, name := range names {
+		list[i] = quote(name)
+	}
+	return list
+}
+
+// quoteExpr returns the Go expression joining the identifiers, each quoted at run time by the dialect d
+func quoteExpr(names []string, sep string) string {
+	list := make([]string, len(names))
+	for i, name := range names {
+		list[i] = fmt.Sprintf("d.Quote(%q)", name)
+	}
+	return strings.Join(list, " + "+strconv.Quote(sep)+" + ")
+}
+
+// sqlType returns the column type for the member's Go type
+func (s *SQLInfo) sqlType(k string) string {
+	if _, ok := s.Enums[k]; ok {
+		return "integer"
+	}
+	if s.Wrap[k] == "dbobj.UnixTime" {
+		return "integer"
+	}
+	switch strings.TrimPrefix(s.Types[k], "*") {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "bool":
+		return "integer"
+	case "float32", "float64":
+		return "real"
+	case "[]byte":
+		return "blob"
+	case "time.Time":
+		return "datetime"
+	}
+	return "text"
+}
+
+// sqlDefault returns the default value for the column definition,
+// quoting it unless it's a number, a keyword such as CURRENT_TIMESTAMP,
+// or an expression in parentheses
+func sqlDefault(v string) string {
+	if _, err := strconv.ParseFloat(v, 64); err == nil {
+		return v
+	}
+	switch strings.ToUpper(v) {
+	case "CURRENT_TIMESTAMP", "CURRENT_DATE", "CURRENT_TIME", "NULL", "TRUE", "FALSE":
+		return v
+	}
+	if strings.HasPrefix(v, "(") || strings.HasPrefix(v, "'") {
+		return v
+	}
+	return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+}
+
+// patches returns the switch cases appending each updatable column's value
+func (s *SQLInfo) patches() []string {
+	cases := make([]string, 0, len(s.Order))
+	for _, k := range s.Order {
+		if _, ok := s.NoUpdate[k]; ok {
+			continue
+		}
+		cases = append(cases, fmt.Sprintf("case %q:\n\targs = append(args, %s)", s.Fields[k], s.value(k)))
+	}
+	return cases
+}
+
+// selected returns the select fields and their member pointers, in declared order
+// so select * aligns with the schema
+func (s *SQLInfo) selected() (columns, pointers []string) {
+	for _, k := range s.members() {
+		columns = append(columns, s.column(k))
+		pointers = append(pointers, s.pointer(k))
+	}
+	return columns, pointers
+}
+
+// value returns the expression for the sql value of member k
+func (s *SQLInfo) value(k string) string {
+	if sep, ok := s.Splits[k]; ok {
+		return fmt.Sprintf("dbobj.Delimited{Strings: &o.%s, Sep: %q}", k, sep)
+	}
+	if wrap, ok := s.Wrap[k]; ok {
+		return fmt.Sprintf("%s(o.%s)", wrap, k)
+	}
+	return "o." + k
+}
+
+// pointer returns the expression for the scan receiver of member k
+func (s *SQLInfo) pointer(k string) string {
+	if sep, ok := s.Splits[k]; ok {
+		return fmt.Sprintf("&dbobj.Delimited{Strings: &o.%s, Sep: %q}", k, sep)
+	}
+	if wrap, ok := s.Wrap[k]; ok {
+		return fmt.Sprintf("(*%s)(&o.%s)", wrap, k)
+	}
+	return "&o." + k
+}
+
+// members returns the names of the sql members, in declared order
+func (s *SQLInfo) members() []string {
+	list := make([]string, 0, len(s.Order)+1)
+	for i, k := range s.Order {
+		if i == s.KeyPos && len(s.KeyName) > 0 {
+			list = append(list, s.KeyName)
+		}
+		if len(k) > 0 {
+			list = append(list, k)
+		}
+	}
+	if s.KeyPos >= len(s.Order) && len(s.KeyName) > 0 {
+		list = append(list, s.KeyName)
+	}
+	return list
+}
+
+// copies returns the statements copying each sql member from o to c
+func (s *SQLInfo) copies() []string {
+	members := s.members()
+	list := make([]string, 0, len(members))
+	for _, k := range members {
+		if s.Types[k] == "[]byte" {
+			list = append(list, fmt.Sprintf("c.%[1]s = append([]byte(nil), o.%[1]s...)", k))
+		} else if s.Types[k] == "[]string" {
+			list = append(list, fmt.Sprintf("c.%[1]s = append([]string(nil), o.%[1]s...)", k))
+		} else if strings.HasPrefix(s.Types[k], "*") {
+			list = append(list, fmt.Sprintf("if o.%[1]s != nil {\nv := *o.%[1]s\nc.%[1]s = &v\n}", k))
+		} else {
+			list = append(list, fmt.Sprintf("c.%[1]s = o.%[1]s", k))
+		}
+	}
+	return list
+}
+
+// wrappable returns an error if the type can't be wrapped by a type of another
+// package, which can only reach its exported fields and can't declare its enum types
+func (s *SQLInfo) wrappable(pkg string) error {
+	if !token.IsExported(s.Name) {
+		return fmt.Errorf("type %s must be exported to be wrapped outside package %s", s.Name, pkg)
+	}
+	for _, k := range s.members() {
+		if !token.IsExported(k) {
+			return fmt.Errorf("type %s field %s must be exported to be wrapped outside package %s", s.Name, k, pkg)
+		}
+		if enum, ok := s.Enums[k]; ok {
+			return fmt.Errorf("type %s field %s has enum type %s, which must be generated in package %s", s.Name, k, enum, pkg)
+		}
+		if k == s.Name {
+			return fmt.Errorf("type %s field %s would be hidden by the embedded %s.%s", s.Name, k, pkg, s.Name)
+		}
+	}
+	return nil
+}
+
+// buildEnums generates the enum types of s not already generated
+func (g *Generator) buildEnums(s *SQLInfo) {
+	if g.enums == nil {
+		g.enums = make(map[string]bool)
+	}
+	for _, k := range s.members() {
+		enum, ok := s.Enums[k]
+		if !ok || g.enums[enum] {
+			continue
+		}
+		g.enums[enum] = true
+		base := "int"
+		if kind := s.Types[k]; kind != enum && strings.HasPrefix(kind, "int") {
+			base = kind
+		}
+		g.Printf(stringEnum, enum, base)
+	}
+}
+
+// column returns the sql column of the member
+func (s *SQLInfo) column(member string) string {
+	if member == s.KeyName {
+		return s.KeyField
+	}
+	return s.Fields[member]
+}
+
+// validations returns the statements checking each member's constraints
+func (s *SQLInfo) validations() []string {
+	var list []string
+	invalid := func(k, cond, reason string) {
+		list = append(list, fmt.Sprintf("if %s {\n\treturn dbobj.Invalid(%q, %q, %q)\n}", cond, s.Table, s.column(k), reason))
+	}
+	for _, k := range s.members() {
+		check, ok := s.Checks[k]
+		if !ok {
+			continue
+		}
+		kind := s.Types[k]
+		if check.NotNull {
+			switch {
+			case kind == "string":
+				invalid(k, fmt.Sprintf("o.%s == \"\"", k), "must not be empty")
+			case kind == "time.Time":
+				invalid(k, fmt.Sprintf("o.%s.IsZero()", k), "must not be zero")
+			case strings.HasPrefix(kind, "*"), strings.HasPrefix(kind, "[]"), strings.HasPrefix(kind, "map["):
+				invalid(k, fmt.Sprintf("o.%s == nil", k), "must not be null")
+			}
+		}
+		if check.MaxLen != "" {
+			invalid(k, fmt.Sprintf("len(o.%s) > %s", k, check.MaxLen), "exceeds max length "+check.MaxLen)
+		}
+		if check.Min != "" {
+			invalid(k, fmt.Sprintf("o.%s < %s", k, check.Min), "less than minimum "+check.Min)
+		}
+	}
+	return list
+}
+
+// compares returns the expressions comparing each sql member of o and other
+func (s *SQLInfo) compares() []string {
+	members := s.members()
+	list := make([]string, 0, len(members))
+	for _, k := range members {
+		switch s.Types[k] {
+		case "[]byte":
+			list = append(list, fmt.Sprintf("string(o.%[1]s) == string(other.%[1]s)", k))
+		case "[]string":
+			list = append(list, fmt.Sprintf("dbobj.StringsEqual(o.%[1]s, other.%[1]s)", k))
+		case "time.Time":
+			list = append(list, fmt.Sprintf("o.%[1]s.Equal(other.%[1]s)", k))
+		case "*time.Time":
+			list = append(list, fmt.Sprintf("(o.%[1]s == nil) == (other.%[1]s == nil) && (o.%[1]s == nil || o.%[1]s.Equal(*other.%[1]s))", k))
+		default:
+			if strings.HasPrefix(s.Types[k], "*") {
+				list = append(list, fmt.Sprintf("(o.%[1]s == nil) == (other.%[1]s == nil) && (o.%[1]s == nil || *o.%[1]s == *other.%[1]s)", k))
+			} else {
+				list = append(list, fmt.Sprintf("o.%[1]s == other.%[1]s", k))
+			}
+		}
+	}
+	return list
 }
 
 // Arguments to format are:
@@ -556,6 +1529,24 @@ const stringMemberPointers = `func (o *%[1]s) MemberPointers() []interface{} {
 
 `
 
+// Arguments to format are:
+//	[1]: constructors of the generated types
+const stringRegistry = `
+// AllObjects holds a constructor for each generated type,
+// for operations across the schema such as creating all tables
+var AllObjects = []func() dbobj.DBObject{
+	%[1]s
+}
+`
+
+// Arguments to format are:
+//	[1]: type name
+const stringFromRow = `func (o *%[1]s) FromRow(r dbobj.RowScanner) error {
+	return r.Scan(o.MemberPointers()...)
+}
+
+`
+
 // Arguments to format are:
 //	[1]: type name
 //	[2]: key field
@@ -565,6 +1556,79 @@ const stringKey = `func (o *%[1]s) Key() int64 {
 
 `
 
+// Arguments to format are:
+//	[1]: type name
+//	[2]: insert of the upsert
+//	[3]: args of the upsert
+//	[4]: switch cases returning the upsert of each unique constraint
+//	[5]: switch cases returning the fields of each unique constraint
+const stringUpsertByUnique = `func (o *%[1]s) UpsertByUnique(d dbobj.Dialect, table, name string) (string, []interface{}) {
+	insert := %[2]s
+	args := []interface{}{%[3]s}
+	switch name {
+	%[4]s
+	}
+	return "", nil
+}
+
+func (o *%[1]s) UniqueFields(name string) []string {
+	switch name {
+	%[5]s
+	}
+	return nil
+}
+
+`
+
+// Arguments to format are:
+//	[1]: type name
+//	[2]: quoted sensitive fields
+const stringSensitive = `func (o *%[1]s) Sensitive() []string {
+	return []string{%[2]s}
+}
+
+`
+
+// Arguments to format are:
+//	[1]: type name
+//	[2]: sql table
+//	[3]: sql key field
+//	[4]: field metadata
+const stringTableInfo = `func (o *%[1]s) TableInfo() dbobj.TableMeta {
+	return dbobj.TableMeta{
+		Table: %[2]q,
+		Key:   %[3]q,
+		Fields: []dbobj.FieldMeta{
+			%[4]s
+		},
+	}
+}
+
+`
+
+// Arguments to format are:
+//	[1]: type name
+//	[2]: create table statement
+const stringSchemaQuery = `func (o *%[1]s) SchemaQuery() string {
+	return %[2]q
+}
+
+`
+
+// Arguments to format are:
+//	[1]: type name
+//	[2]: quoted key fields
+//	[3]: key values
+const stringKeyValues = `func (o *%[1]s) KeyFields() []string {
+	return []string{%[2]s}
+}
+
+func (o *%[1]s) KeyValues() []interface{} {
+	return []interface{}{%[3]s}
+}
+
+`
+
 // Arguments to format are:
 //	[1]: type name
 //	[2]: key field
@@ -639,6 +1703,15 @@ const stringSelectFields = `func (o *%[1]s) SelectFields() string {
 
 `
 
+// Arguments to format are:
+//	[1]: type name
+//	[2]: table qualified select fields
+const stringQualifiedSelectFields = `func (o *%[1]s) QualifiedSelectFields() string {
+	return %[2]q
+}
+
+`
+
 // Arguments to format are:
 //	[1]: type name
 //	[2]: insert fields
@@ -650,7 +1723,23 @@ const stringInsertFields = `func (o *%[1]s) InsertFields() string {
 
 // Arguments to format are:
 //	[1]: type name
-const stringNewObj = `func (o %[1]s) NewObj() interface{} {
+//	[2]: source package name
+const stringWrapper = `
+// %[1]s wraps %[2]s.%[1]s, as its methods can only be declared in package %[2]s
+type %[1]s struct {
+	%[2]s.%[1]s
+}
+`
+
+// Arguments to format are:
+//	[1]: type name
+const stringAssert = `var _ dbobj.DBObject = (*%[1]s)(nil)
+
+`
+
+// Arguments to format are:
+//	[1]: type name
+const stringNewObj = `func (o *%[1]s) NewObj() interface{} {
 	return new(%[1]s)
 }
 
@@ -667,6 +1756,15 @@ type %[1]sList []%[1]s
 `
 */
 
+// Arguments to format are:
+//	[1]: type name
+//	[2]: quoted sql columns
+const stringColumns = `func (o *%[1]s) Columns() []string {
+	return []string{%[2]s}
+}
+
+`
+
 // Arguments to format are:
 //	[1]: type name
 //	[2]: member names
@@ -676,16 +1774,34 @@ const stringNames = `func (o *%[1]s) Names() []string {
 
 `
 
-func auditString(name, u, t string) string {
-	args := []interface{}{name}
-	stringAudit := "func (o *%s) ModifiedBy(user int64, t time.Time) {\n"
-	if len(u) > 0 {
-		stringAudit += "o.%s = &user\n"
-		args = append(args, u)
+func auditString(s *SQLInfo) string {
+	audit := auditMethod(s, "ModifiedBy", s.UserField, s.TimeField)
+	if len(s.CreatedBy) > 0 || len(s.CreatedAt) > 0 {
+		audit += auditMethod(s, "CreatedBy", s.CreatedBy, s.CreatedAt)
 	}
-	if len(t) > 0 {
-		stringAudit += "o.%s = t\n"
-		args = append(args, t)
+	return audit
+}
+
+// auditMethod returns the named method setting the user and time fields, if any
+func auditMethod(s *SQLInfo, method, user, when string) string {
+	args := []interface{}{s.Name, method}
+	stringAudit := "func (o *%s) %s(user int64, t time.Time) {\n"
+	// pointer fields distinguish never modified (nil) from zero values
+	if len(user) > 0 {
+		if strings.HasPrefix(s.Types[user], "*") {
+			stringAudit += "o.%s = &user\n"
+		} else {
+			stringAudit += "o.%s = user\n"
+		}
+		args = append(args, user)
+	}
+	if len(when) > 0 {
+		if strings.HasPrefix(s.Types[when], "*") {
+			stringAudit += "o.%s = &t\n"
+		} else {
+			stringAudit += "o.%s = t\n"
+		}
+		args = append(args, when)
 	}
 	stringAudit += "}\n\n\n"
 	return fmt.Sprintf(stringAudit, args...)
@@ -697,7 +1813,125 @@ func auditString(name, u, t string) string {
 //	[3]: select fields
 //	[4]: where fields
 const stringSQLGet = `func (o *%[1]s) SQLGet(keys ...interface{}) string {
-	return "select %[3]s from %[2]s where %[4]s;"
+	return %[2]q
+}
+
+`
+
+// Arguments to format are:
+//	[1]: type name
+//	[2]: true if the key is assigned by the database
+const stringAutoIncrement = `func (o *%[1]s) AutoIncrement() bool {
+	return %[2]t
+}
+
+`
+
+// Arguments to format are:
+//	[1]: type name
+//	[2]: column name to member pointer pairs
+const stringFieldMap = `func (o *%[1]s) FieldMap() map[string]interface{} {
+	return map[string]interface{}{
+		%[2]s,
+	}
+}
+
+`
+
+// Arguments to format are:
+//	[1]: type name
+//	[2]: member copy statements
+const stringClone = `func (o *%[1]s) Clone() *%[1]s {
+	c := new(%[1]s)
+	%[2]s
+	return c
+}
+
+`
+
+// Arguments to format are:
+//	[1]: type name
+//	[2]: member comparisons
+const stringEqual = `func (o *%[1]s) Equal(other *%[1]s) bool {
+	if o == nil || other == nil {
+		return o == other
+	}
+	return %[2]s
+}
+
+`
+
+// Arguments to format are:
+//	[1]: type name
+//	[2]: column switch cases
+//	[3]: key field
+//	[4]: key name
+const stringUpdateFieldQuery = `func (o *%[1]s) UpdateFieldQuery(d dbobj.Dialect, table string, cols ...string) (string, []interface{}, error) {
+	query := "update " + d.Quote(table) + " set "
+	args := make([]interface{}, 0, len(cols)+1)
+	for i, col := range cols {
+		switch col {
+		%[2]s
+		default:
+			return "", nil, dbobj.UnknownColumn(table, col)
+		}
+		if i > 0 {
+			query += ","
+		}
+		query += d.Quote(col) + "=" + d.Placeholder(len(args))
+	}
+	return query + " where " + d.Quote("%[3]s") + "=" + d.Placeholder(len(args)+1), append(args, o.%[4]s), nil
+}
+
+`
+
+// Arguments to format are:
+//	[1]: enum type name
+//	[2]: underlying integer type
+const stringEnum = `// %[1]s is an enumerated type stored as an integer column
+type %[1]s %[2]s
+
+func (e %[1]s) Value() (dbobj.Value, error) {
+	return int64(e), nil
+}
+
+func (e *%[1]s) Scan(src interface{}) error {
+	i, err := dbobj.ScanInt(src)
+	*e = %[1]s(i)
+	return err
+}
+
+`
+
+// Arguments to format are:
+//	[1]: type name
+//	[2]: constraint checks
+const stringValidate = `func (o *%[1]s) Validate() error {
+	%[2]s
+	return nil
+}
+
+`
+
+// Arguments to format are:
+//	[1]: type name
+//	[2]: table name
+//	[3]: select fields
+//	[4]: member values
+const stringJSON = `func (o *%[1]s) MarshalJSON() ([]byte, error) {
+	return dbobj.MarshalColumns(o.Columns(), %[2]s)
+}
+
+func (o *%[1]s) UnmarshalJSON(data []byte) error {
+	return dbobj.UnmarshalColumns(data, map[string]interface{}{
+		%[3]s
+	})
+}
+
+`
+
+const stringString = `func (o *%[1]s) String() string {
+	return dbobj.FormatObject(%[2]q, %[3]q, %[4]s)
 }
 
 `
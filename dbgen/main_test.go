@@ -1,10 +1,26 @@
 package main
 
 import (
+	"bytes"
 	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"log"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/paulstuart/dbobj"
 	//dbu "github.com/paulstuart/dbutil"
 	sqlite "github.com/paulstuart/sqlite"
 )
@@ -29,3 +45,1519 @@ func TestInit(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestParseError(t *testing.T) {
+	var g Generator
+	const bad = "package main\n\ntype broken struct {\n"
+	if err := g.parsePackage(".", []string{"broken.go"}, bad); err == nil {
+		t.Fatal("expected error for malformed source")
+	} else {
+		t.Log("parse error:", err)
+	}
+}
+
+func TestParseDirError(t *testing.T) {
+	var g Generator
+	if err := g.parsePackageDir("no/such/dir"); err == nil {
+		t.Fatal("expected error for missing directory")
+	}
+	if err := g.generate(""); err == nil {
+		t.Fatal("expected error generating without a parsed package")
+	}
+}
+
+// generateSource runs the generator over src and returns the formatted output
+func generateSource(t *testing.T, src, typeName string) string {
+	t.Helper()
+	var g Generator
+	if err := g.parsePackage(".", []string{"source.go"}, src); err != nil {
+		t.Fatal(err)
+	}
+	g.header("test")
+	if err := g.generate(typeName); err != nil {
+		t.Fatal(err)
+	}
+	out, err := g.format()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+// generateFiles runs the generator over the named files and returns the formatted output
+func generateFiles(t *testing.T, typeName string, names ...string) string {
+	t.Helper()
+	var g Generator
+	if err := g.parsePackageFiles(names); err != nil {
+		t.Fatal(err)
+	}
+	g.header("test")
+	if err := g.generate(typeName); err != nil {
+		t.Fatal(err)
+	}
+	out, err := g.format()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestNaturalKey(t *testing.T) {
+	const src = `package main
+
+type natural struct {
+	ID   int64  ` + "`" + `sql:"id" key:"true" autoincrement:"false" table:"natural"` + "`" + `
+	Name string ` + "`" + `sql:"name"` + "`" + `
+}
+`
+	out := generateSource(t, src, "natural")
+	for _, want := range []string{
+		"return []interface{}{o.ID, o.Name}",
+		"return \"id,name\"",
+		"AutoIncrement() bool {\n\treturn false",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("generated code missing %q:\n%s", want, out)
+		}
+	}
+}
+
+const keylessSource = `package main
+
+type keyless struct {
+	Name string ` + "`" + `sql:"name" table:"keyless"` + "`" + `
+	Kind int    ` + "`" + `sql:"kind"` + "`" + `
+}
+`
+
+func TestNoKeyWarning(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	generateSource(t, keylessSource, "keyless")
+	if !strings.Contains(buf.String(), "warning: type keyless (table keyless) has no key field") {
+		t.Errorf("expected warning for keyless type, got: %q", buf.String())
+	}
+}
+
+func TestNoKeyStrict(t *testing.T) {
+	*strict = true
+	defer func() { *strict = false }()
+	var g Generator
+	if err := g.parsePackage(".", []string{"source.go"}, keylessSource); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.generate("keyless"); err == nil {
+		t.Fatal("expected error for keyless type in strict mode")
+	}
+}
+
+func TestPointerReceivers(t *testing.T) {
+	out := generateFiles(t, "testStruct", "struct_test.go")
+	for _, want := range []string{
+		"var _ dbobj.DBObject = (*testStruct)(nil)",
+		"func (o *testStruct) NewObj() interface{}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("generated code missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "func (o testStruct)") {
+		t.Errorf("generated code has value receiver:\n%s", out)
+	}
+}
+
+func TestFieldMap(t *testing.T) {
+	out := generateFiles(t, "testStruct", "struct_test.go")
+	const want = `func (o *testStruct) FieldMap() map[string]interface{} {
+	return map[string]interface{}{
+		"id":      &o.ID,
+		"name":    &o.Name,
+		"kind":    &o.Kind,
+		"data":    &o.Data,
+		"created": (*dbobj.NullableTime)(&o.Created),
+	}
+}`
+	if !strings.Contains(out, want) {
+		t.Errorf("generated code missing FieldMap:\n%s", out)
+	}
+}
+
+func TestUnixTime(t *testing.T) {
+	const src = `package main
+
+import "time"
+
+type epoch struct {
+	ID    int64     ` + "`" + `sql:"id" key:"true" table:"epochs"` + "`" + `
+	Stamp time.Time ` + "`" + `sql:"stamp" timefmt:"unix"` + "`" + `
+}
+`
+	out := generateSource(t, src, "epoch")
+	for _, want := range []string{
+		"return []interface{}{dbobj.UnixTime(o.Stamp)}",
+		"return []interface{}{&o.ID, (*dbobj.UnixTime)(&o.Stamp)}",
+		`"stamp": (*dbobj.UnixTime)(&o.Stamp),`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("generated code missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestMultipleFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dbgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	const base = `package models
+
+type Base struct {
+	ID int64 ` + "`" + `sql:"id" key:"true" table:"users"` + "`" + `
+}
+`
+	const user = `package models
+
+type User struct {
+	Base
+	Name  string ` + "`" + `sql:"name"` + "`" + `
+	Email string ` + "`" + `sql:"email"` + "`" + `
+}
+`
+	baseFile := filepath.Join(dir, "base.go")
+	userFile := filepath.Join(dir, "user.go")
+	if err := ioutil.WriteFile(baseFile, []byte(base), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(userFile, []byte(user), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := generateFiles(t, "User", userFile, baseFile)
+	for _, want := range []string{
+		`return "id,name,email"`,
+		`return "users"`,
+		"return []interface{}{&o.ID, &o.Name, &o.Email}",
+		"func (o *User) SetID(id int64) {\n\to.ID = id",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("generated code missing %q:\n%s", want, out)
+		}
+	}
+}
+
+// TestGenerated confirms generated_test.go is current with the generator
+func TestGenerated(t *testing.T) {
+	var g Generator
+	if err := g.parsePackageFiles([]string{"struct_test.go"}); err != nil {
+		t.Fatal(err)
+	}
+	*stringer, *jsonFlag = true, true
+	defer func() { *stringer, *jsonFlag = false, false }()
+	g.header("-stringer -registry -json -output generated_test.go -type testStruct,auditStruct,listStruct,stampStruct,userStruct,rowidStruct,groupStruct struct_test.go")
+	for _, typeName := range []string{"testStruct", "auditStruct", "listStruct", "stampStruct", "userStruct", "rowidStruct", "groupStruct"} {
+		if err := g.generate(typeName); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g.buildRegistry()
+	current, err := ioutil.ReadFile("generated_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := g.format()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(current, src) {
+		t.Error("generated_test.go is stale; run go generate")
+	}
+}
+
+func TestClone(t *testing.T) {
+	o := &testStruct{ID: 1, Name: "original", Kind: 2, Data: []byte("abc")}
+	c := o.Clone()
+	if c.ID != o.ID || c.Name != o.Name || c.Kind != o.Kind || string(c.Data) != string(o.Data) {
+		t.Fatalf("clone mismatch: %+v vs %+v", c, o)
+	}
+	c.Name = "clone"
+	c.Data[0] = 'X'
+	if o.Name != "original" {
+		t.Errorf("original name changed: %q", o.Name)
+	}
+	if string(o.Data) != "abc" {
+		t.Errorf("original data changed: %q", o.Data)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	now := time.Now()
+	a := &testStruct{ID: 1, Name: "a", Data: []byte("abc"), Created: now}
+	b := a.Clone()
+	b.Created = now.UTC()
+	if !a.Equal(b) {
+		t.Fatalf("expected clone to be equal: %+v vs %+v", a, b)
+	}
+	b.Data[0] = 'X'
+	if a.Equal(b) {
+		t.Error("expected different data to be unequal")
+	}
+	if a.Equal(nil) {
+		t.Error("expected nil to be unequal")
+	}
+}
+
+func TestEnum(t *testing.T) {
+	const src = `package main
+
+type Role int
+
+type user struct {
+	ID    int64 ` + "`" + `sql:"id" key:"true" table:"users"` + "`" + `
+	Role  Role  ` + "`" + `sql:"role" enum:"Role"` + "`" + `
+	Level int8  ` + "`" + `sql:"level" enum:"Level"` + "`" + `
+	Other int8  ` + "`" + `sql:"other" enum:"Level"` + "`" + `
+}
+`
+	out := generateSource(t, src, "user")
+	for _, want := range []string{
+		"type Role int\n",
+		"type Level int8\n",
+		"func (e Role) Value() (dbobj.Value, error) {",
+		"func (e *Level) Scan(src interface{}) error {",
+		"return []interface{}{&o.ID, &o.Role, (*Level)(&o.Level), (*Level)(&o.Other)}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("generated code missing %q:\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, "type Level "); n != 1 {
+		t.Errorf("expected Level to be generated once, got %d", n)
+	}
+}
+
+func TestEnumRoundTrip(t *testing.T) {
+	db, err := dbobj.NewDBU(":memory:", true, sqlite.Open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, _, err = db.Exec(testSchema); err != nil {
+		t.Fatal(err)
+	}
+	const kind testKind = 3
+	o := &testStruct{Name: "enum", Kind: kind}
+	if err := db.Add(o); err != nil {
+		t.Fatal(err)
+	}
+	var raw int
+	if err := db.DB().QueryRow("select kind from teststruct where id=?", o.ID).Scan(&raw); err != nil {
+		t.Fatal(err)
+	}
+	if raw != int(kind) {
+		t.Errorf("expected column to store %d, got %d", kind, raw)
+	}
+	got := &testStruct{}
+	if err := db.FindByID(got, o.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got.Kind != kind {
+		t.Errorf("expected kind %d, got %d", kind, got.Kind)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	const src = `package main
+
+import "time"
+
+type account struct {
+	ID      int64     ` + "`" + `sql:"id" key:"true" table:"accounts"` + "`" + `
+	Name    string    ` + "`" + `sql:"name" notnull:"true" maxlen:"32"` + "`" + `
+	Opened  time.Time ` + "`" + `sql:"opened" notnull:"true"` + "`" + `
+	Balance int       ` + "`" + `sql:"balance" min:"0"` + "`" + `
+}
+`
+	out := generateSource(t, src, "account")
+	for _, want := range []string{
+		`if o.Name == "" {`,
+		`return dbobj.Invalid("accounts", "name", "must not be empty")`,
+		"if len(o.Name) > 32 {",
+		"if o.Opened.IsZero() {",
+		"if o.Balance < 0 {",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("generated code missing %q:\n%s", want, out)
+		}
+	}
+	if out := generateSource(t, keylessSource, "keyless"); strings.Contains(out, "Validate()") {
+		t.Errorf("expected no Validate without constraints:\n%s", out)
+	}
+
+	o := &testStruct{Name: strings.Repeat("x", 256)}
+	if err := o.Validate(); err == nil {
+		t.Error("expected long name to be invalid")
+	}
+	o.Name = "ok"
+	if err := o.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUnexportedFields(t *testing.T) {
+	const src = `package main
+
+import "sync"
+
+type mixed struct {
+	sync.Mutex
+	ID      int64  ` + "`" + `sql:"id" key:"true" table:"mixed"` + "`" + `
+	Name    string ` + "`" + `sql:"name"` + "`" + `
+	astring string
+	anint   int    ` + "`" + `json:"anint" audit:"user"` + "`" + `
+	Kind    int    ` + "`" + `sql:"kind"` + "`" + `
+}
+`
+	out := generateSource(t, src, "mixed")
+	for _, want := range []string{
+		"return []interface{}{&o.ID, &o.Name, &o.Kind}",
+		`return "id,name,kind"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("generated code missing %q:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"astring", "anint", "Mutex"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("generated code should not contain %q:\n%s", unwanted, out)
+		}
+	}
+}
+
+func TestKeyNotFirst(t *testing.T) {
+	const src = `package main
+
+type middle struct {
+	Name string ` + "`" + `sql:"name" table:"middle"` + "`" + `
+	ID   int64  ` + "`" + `sql:"id" key:"true"` + "`" + `
+	Kind int    ` + "`" + `sql:"kind"` + "`" + `
+}
+
+type natural struct {
+	Name string ` + "`" + `sql:"name" table:"natural"` + "`" + `
+	Code string ` + "`" + `sql:"code" key:"true" autoincrement:"false"` + "`" + `
+}
+`
+	out := generateSource(t, src, "middle")
+	for _, want := range []string{
+		`return "name,id,kind"`,
+		"return []interface{}{&o.Name, &o.ID, &o.Kind}",
+		"return []interface{}{o.Name, o.Kind}",
+		"return []interface{}{o.Name, o.Kind, o.ID}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("generated code missing %q:\n%s", want, out)
+		}
+	}
+	out = generateSource(t, src, "natural")
+	for _, want := range []string{
+		`return "name,code"`,
+		"return []interface{}{&o.Name, &o.Code}",
+		"return []interface{}{o.Name, o.Code}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("generated code missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestStringer(t *testing.T) {
+	if out := generateSource(t, keylessSource, "keyless"); strings.Contains(out, "String()") {
+		t.Errorf("expected no String method without -stringer:\n%s", out)
+	}
+	o := &testStruct{ID: 1, Name: "abc", Kind: 23, Data: []byte("xyz")}
+	const want = "teststruct{id=1 name=abc kind=23 data=xyz created=0001-01-01 00:00:00 +0000 UTC}"
+	if got := o.String(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	if out := generateSource(t, keylessSource, "keyless"); strings.Contains(out, "MarshalJSON") {
+		t.Errorf("expected no MarshalJSON method without -json:\n%s", out)
+	}
+	created := time.Date(2020, 2, 3, 4, 5, 6, 0, time.UTC)
+	o := &testStruct{ID: 1, Name: "abc", Kind: 23, Data: []byte("xyz"), Created: created}
+	data, err := json.Marshal(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key.(string))
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := strings.Join(keys, ","); got != "id,name,kind,data,created" {
+		t.Errorf("expected keys id,name,kind,data,created, got %s", got)
+	}
+	if !strings.Contains(string(data), `"created":"2020-02-03T04:05:06Z"`) {
+		t.Errorf("expected RFC 3339 time in %s", data)
+	}
+	var back testStruct
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if !back.Equal(o) {
+		t.Errorf("expected %v, got %v", o, &back)
+	}
+}
+
+func TestQualifiedSelectFields(t *testing.T) {
+	const src = `package main
+
+type user struct {
+	ID   int64  ` + "`" + `sql:"id" key:"true" table:"users"` + "`" + `
+	Name string ` + "`" + `sql:"name"` + "`" + `
+}
+
+type group struct {
+	ID   int64  ` + "`" + `sql:"id" key:"true" table:"groups"` + "`" + `
+	Name string ` + "`" + `sql:"name"` + "`" + `
+}
+`
+	for typeName, want := range map[string]string{
+		"user":  `return "users.id,users.name"`,
+		"group": `return "groups.id,groups.name"`,
+	} {
+		if out := generateSource(t, src, typeName); !strings.Contains(out, want) {
+			t.Errorf("generated code missing %q:\n%s", want, out)
+		}
+	}
+	o := &testStruct{}
+	const want = "teststruct.id,teststruct.name,teststruct.kind,teststruct.data,teststruct.created"
+	if got := dbobj.QualifiedFields(o); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestPointerAudit(t *testing.T) {
+	out := generateFiles(t, "auditStruct", "struct_test.go")
+	for _, want := range []string{
+		"o.UserID = &user",
+		"o.Modified = &t",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("generated code missing %q:\n%s", want, out)
+		}
+	}
+
+	// auditStruct is compiled from generated_test.go
+	o := &auditStruct{ID: 1}
+	c := o.Clone()
+	if !o.Equal(c) {
+		t.Error("expected unmodified clone to be equal")
+	}
+	now := time.Now()
+	o.ModifiedBy(42, now)
+	if o.UserID == nil || *o.UserID != 42 {
+		t.Errorf("expected user 42, got %v", o.UserID)
+	}
+	if o.Modified == nil || !o.Modified.Equal(now) {
+		t.Errorf("expected modified %v, got %v", now, o.Modified)
+	}
+	if o.Equal(c) {
+		t.Error("expected modified object to differ from its clone")
+	}
+	c = o.Clone()
+	if !o.Equal(c) || c.Modified == o.Modified {
+		t.Error("expected clone to be equal with its own time pointer")
+	}
+}
+
+func TestCreatedBy(t *testing.T) {
+	db, err := dbobj.NewDBU(":memory:", true, sqlite.Open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	o := &stampStruct{Name: "stamped"}
+	if _, _, err = db.Exec(o.SchemaQuery()); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddAs(o, 7); err != nil {
+		t.Fatal(err)
+	}
+	added := &stampStruct{}
+	if err := db.FindByID(added, o.ID); err != nil {
+		t.Fatal(err)
+	}
+	if added.Creator != 7 || added.Created.IsZero() || added.Updater != 0 || !added.Updated.IsZero() {
+		t.Errorf("expected only creation audit fields after add, got %+v", added)
+	}
+	added.Name = "updated"
+	if err := db.SaveAs(added, 9); err != nil {
+		t.Fatal(err)
+	}
+	saved := &stampStruct{}
+	if err := db.FindByID(saved, o.ID); err != nil {
+		t.Fatal(err)
+	}
+	if saved.Creator != 7 || !saved.Created.Equal(added.Created) {
+		t.Errorf("expected creation audit fields to be kept, got %+v", saved)
+	}
+	if saved.Updater != 9 || saved.Updated.Before(saved.Created) {
+		t.Errorf("expected update audit fields after save, got %+v", saved)
+	}
+}
+
+func TestUpsertBy(t *testing.T) {
+	db, err := dbobj.NewDBU(":memory:", true, sqlite.Open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, _, err = db.Exec(userSchema); err != nil {
+		t.Fatal(err)
+	}
+	first := &userStruct{Email: "bob@example.com", Name: "Bob"}
+	if err := db.UpsertBy(first, "email"); err != nil {
+		t.Fatal(err)
+	}
+	other := &userStruct{Email: "sue@example.com", Name: "Sue"}
+	if err := db.UpsertBy(other, "email"); err != nil {
+		t.Fatal(err)
+	}
+	if first.ID == 0 || other.ID <= first.ID {
+		t.Errorf("expected autoincremented ids, got %d and %d", first.ID, other.ID)
+	}
+	again := &userStruct{Email: "bob@example.com", Name: "Robert"}
+	if err := db.UpsertBy(again, "email"); err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != first.ID {
+		t.Errorf("expected the existing id %d, got %d", first.ID, again.ID)
+	}
+	if count, _ := db.Count(&userStruct{}, ""); count != 2 {
+		t.Errorf("expected 2 users, got %d", count)
+	}
+	got := &userStruct{}
+	if err := db.FindByID(got, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Robert" {
+		t.Errorf("expected name to be updated, got %+v", got)
+	}
+	if err := db.UpsertBy(again, "name"); !errors.Is(err, dbobj.ErrUnknownUnique) {
+		t.Errorf("expected ErrUnknownUnique, got %v", err)
+	}
+	for d, want := range map[dbobj.Dialect]string{
+		dbobj.Postgres: "insert into users (email,name) values ($1,$2) on conflict (email) do update set name=excluded.name",
+		dbobj.MySQL:    "insert into users (email,name) values (?,?) on duplicate key update name=values(name)",
+	} {
+		if query, args := again.UpsertByUnique(d, "users", "email"); query != want || len(args) != 2 {
+			t.Errorf("%s: expected %q with 2 args, got %q %v", d, want, query, args)
+		}
+	}
+	if query, _ := again.UpsertByUnique(dbobj.SQLite, "users_2024", "email"); !strings.HasPrefix(query, "insert into users_2024 ") {
+		t.Errorf("expected the insert into the given table, got %q", query)
+	}
+}
+
+func TestUpsertByCache(t *testing.T) {
+	db, err := dbobj.NewDBU(":memory:", true, sqlite.Open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, _, err = db.Exec((&userStruct{}).SchemaQuery()); err != nil {
+		t.Fatal(err)
+	}
+	db.EnableCache(time.Minute)
+	first := &userStruct{Email: "bob@example.com", Name: "Bob"}
+	if err := db.UpsertBy(first, "email"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.FindByID(&userStruct{}, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpsertBy(&userStruct{Email: "bob@example.com", Name: "Robert"}, "email"); err != nil {
+		t.Fatal(err)
+	}
+	got := &userStruct{}
+	if err := db.FindByID(got, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Robert" {
+		t.Errorf("expected the cached record to be invalidated, got %+v", got)
+	}
+}
+
+func TestRowID(t *testing.T) {
+	o := &rowidStruct{}
+	if o.KeyField() != "rowid" {
+		t.Errorf("expected key field rowid, got %q", o.KeyField())
+	}
+	if schema := o.SchemaQuery(); schema != "create table if not exists notes (note text)" {
+		t.Errorf("expected the rowid to be left out of the schema, got %q", schema)
+	}
+	db, err := dbobj.NewDBU(":memory:", true, sqlite.Open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, _, err = db.Exec(rowidSchema); err != nil {
+		t.Fatal(err)
+	}
+	first := &rowidStruct{Note: "first"}
+	second := &rowidStruct{Note: "second"}
+	for _, o := range []*rowidStruct{first, second} {
+		if err := db.Add(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if first.ID != 1 || second.ID != 2 {
+		t.Errorf("expected rowids 1 and 2, got %d and %d", first.ID, second.ID)
+	}
+	got := &rowidStruct{}
+	if err := db.FindByID(got, second.ID); err != nil {
+		t.Fatal(err)
+	}
+	if *got != *second {
+		t.Errorf("expected %+v, got %+v", second, got)
+	}
+	got.Note = "updated"
+	if err := db.Save(got); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.FindSelf(second); err != nil {
+		t.Fatal(err)
+	}
+	if second.Note != "updated" {
+		t.Errorf("expected the note to be saved by rowid, got %+v", second)
+	}
+}
+
+func TestRowIDWithKey(t *testing.T) {
+	const (
+		key   = "\tID    int64  `sql:\"id\" key:\"true\" table:\"notes\"`\n"
+		rowid = "\tRowID int64  `rowid:\"true\"`\n"
+		both  = "\tRowID int64  `rowid:\"true\" key:\"true\" table:\"notes\"`\n"
+	)
+	for _, fields := range []string{key + rowid, rowid + key, both} {
+		src := "package main\n\ntype note struct {\n" + fields + "\tNote  string `sql:\"note\"`\n}\n"
+		var g Generator
+		if err := g.parsePackage(".", []string{"source.go"}, src); err != nil {
+			t.Fatal(err)
+		}
+		g.header("test")
+		if err := g.generate("note"); err == nil || !strings.Contains(err.Error(), "rowid and key are mutually exclusive") {
+			t.Errorf("expected rowid and key to be rejected, got %v for:\n%s", err, src)
+		}
+	}
+}
+
+// checkGenerated type-checks the source and its generated code against
+// a stand-in dbobj package declaring the given DBObject interface
+func checkGenerated(t *testing.T, src, typeName, iface string) error {
+	t.Helper()
+	out := generateSource(t, src, typeName)
+	fset := token.NewFileSet()
+	parse := func(name, text string) *ast.File {
+		f, err := parser.ParseFile(fset, name, text, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	dbobjPkg := fakeDBObj(t, fset, iface)
+	std := importer.Default()
+	config := types.Config{
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			if path == dbobjPkg.Path() {
+				return dbobjPkg, nil
+			}
+			return std.Import(path)
+		}),
+	}
+	files := []*ast.File{parse("source.go", src), parse("generated.go", out)}
+	_, err := config.Check("main", fset, files, nil)
+	return err
+}
+
+// fakeDBObj type-checks a stand-in for the dbobj package, whose DBObject has the methods of iface
+func fakeDBObj(t *testing.T, fset *token.FileSet, iface string) *types.Package {
+	t.Helper()
+	fake, err := parser.ParseFile(fset, "dbobj.go", "package dbobj\n\ntype DBObject interface {\n"+iface+"\n}\n\ntype RowScanner interface {\nScan(...interface{}) error\n}\n\ntype TableMeta struct {\nTable, Key string\nFields []FieldMeta\n}\n\ntype FieldMeta struct {\nGoName, SQLName, GoType string\n}\n\ntype Dialect int\n\nfunc (d Dialect) Placeholder(i int) string { return \"?\" }\n\nfunc (d Dialect) Placeholders(start, n int) string { return \"?\" }\n\nfunc (d Dialect) Quote(name string) string { return name }\n\nconst (\nSQLite Dialect = iota\nPostgres\nMySQL\n)\n\nfunc UnknownColumn(table, column string) error { return nil }\n", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := (&types.Config{}).Check("github.com/paulstuart/dbobj", fset, []*ast.File{fake}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkg
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) {
+	return f(path)
+}
+
+func TestCheck(t *testing.T) {
+	const src = `package main
+
+type user struct {
+	ID   int64  ` + "`" + `sql:"id" key:"true" table:"users"` + "`" + `
+	Role int    ` + "`" + `sql:"role" enum:"userRole"` + "`" + `
+}
+
+func table(u *user) string {
+	var _ userRole
+	_ = AllObjects
+	return u.TableName()
+}
+`
+	var g Generator
+	if err := g.parsePackage(".", []string{"source.go"}, src); err != nil {
+		t.Errorf("expected errors due to generated code to be tolerated, got %v", err)
+	}
+	broken := src + "\nvar count int = \"none\"\n"
+	if err := g.parsePackage(".", []string{"source.go"}, broken); err == nil || !strings.Contains(err.Error(), "cannot use") {
+		t.Errorf("expected other type check errors to fail, got %v", err)
+	}
+	// every generated method must be known, or using it before generation fails
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "generated_test.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, decl := range f.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv != nil && !generatedMethods[fn.Name.Name] {
+			t.Errorf("generated method %s is missing from generatedMethods", fn.Name.Name)
+		}
+	}
+}
+
+func TestBuildTags(t *testing.T) {
+	*buildTags = "sqlite"
+	defer func() { *buildTags = "" }()
+	out := generateSource(t, keylessSource, "keyless")
+	want := "//go:build sqlite\n// +build sqlite\n\npackage main\n"
+	if !strings.Contains(out, want) {
+		t.Errorf("expected build constraint before the package clause:\n%s", out)
+	}
+	if strings.Index(out, "//go:build") > strings.Index(out, "package main") {
+		t.Errorf("expected build constraint to precede the package clause:\n%s", out)
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "generated.go", out, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Doc != nil {
+		t.Errorf("expected build constraint not to be the package doc: %q", f.Doc.Text())
+	}
+	if _, err := buildConstraint("sqlite &&"); err == nil {
+		t.Error("expected invalid build tags to fail")
+	}
+	*buildTags = ""
+	if out := generateSource(t, keylessSource, "keyless"); strings.Contains(out, "//go:build") {
+		t.Errorf("expected no build constraint without tags:\n%s", out)
+	}
+}
+
+func TestStaleNames(t *testing.T) {
+	const src = `package main
+
+type widget struct {
+	ID    int64  ` + "`" + `sql:"id" key:"true" table:"widgets"` + "`" + `
+	Name  string ` + "`" + `sql:"name"` + "`" + `
+	Color string ` + "`" + `sql:"color"` + "`" + `
+}
+
+func (w *widget) Names() []string {
+	return []string{"ID", "Name"}
+}
+`
+	generate := func(src string) error {
+		var g Generator
+		if err := g.parsePackage(".", []string{"source.go"}, src); err != nil {
+			t.Fatal(err)
+		}
+		g.header("test")
+		return g.generate("widget")
+	}
+	err := generate(src)
+	if err == nil || !strings.Contains(err.Error(), "Names lists 1 fields [Name] but 2 are sql tagged [Name Color]") {
+		t.Errorf("expected stale Names to be flagged, got %v", err)
+	}
+	current := strings.Replace(src, `"ID", "Name"}`, `"ID", "Name", "Color"}`, 1)
+	if err := generate(current); err != nil {
+		t.Errorf("expected matching Names to pass, got %v", err)
+	}
+	shared := strings.Replace(src, "Name  string ", "Name, Alias string ", 1)
+	err = generate(strings.Replace(shared, `"ID", "Name"}`, `"ID", "Name", "Color"}`, 1))
+	if err == nil || !strings.Contains(err.Error(), `fields Name and Alias share the tag sql:"name"; declare them separately`) {
+		t.Errorf("expected the shared tag to be rejected, got %v", err)
+	}
+}
+
+func TestTargetPackage(t *testing.T) {
+	const src = `package models
+
+type User struct {
+	ID   int64  ` + "`" + `sql:"id" key:"true" table:"users"` + "`" + `
+	Name string ` + "`" + `sql:"name"` + "`" + `
+}
+`
+	g := Generator{target: "gen", source: "example.com/app/models"}
+	if err := g.parsePackage(".", []string{"source.go"}, src); err != nil {
+		t.Fatal(err)
+	}
+	g.header("test")
+	if err := g.generate("User"); err != nil {
+		t.Fatal(err)
+	}
+	g.buildRegistry()
+	out, err := g.format()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"package gen\n", `"example.com/app/models"`, "\tmodels.User\n"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+	// the wrapper must compile in its own package against the source package
+	fset := token.NewFileSet()
+	parse := func(name, text string) *ast.File {
+		f, err := parser.ParseFile(fset, name, text, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	models, err := (&types.Config{}).Check(g.source, fset, []*ast.File{parse("source.go", src)}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbobjPkg := fakeDBObj(t, fset, "TableName() string\nKey() int64")
+	std := importer.Default()
+	config := types.Config{
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			switch path {
+			case dbobjPkg.Path():
+				return dbobjPkg, nil
+			case models.Path():
+				return models, nil
+			}
+			return std.Import(path)
+		}),
+	}
+	const use = `package gen
+
+import "example.com/app/models"
+
+func unwrap(u models.User) models.User {
+	w := &User{User: u}
+	_ = w.TableName()
+	return w.Clone().User
+}
+`
+	files := []*ast.File{parse("generated.go", string(out)), parse("use.go", use)}
+	if _, err := config.Check("gen", fset, files, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTargetPackageUnexported(t *testing.T) {
+	const src = `package models
+
+type User struct {
+	ID   int64  ` + "`" + `sql:"id" key:"true" table:"users"` + "`" + `
+	name string ` + "`" + `sql:"name"` + "`" + `
+}
+`
+	g := Generator{target: "gen", source: "example.com/app/models"}
+	if err := g.parsePackage(".", []string{"source.go"}, src); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.generate("User"); err == nil || !strings.Contains(err.Error(), "field name must be exported") {
+		t.Errorf("expected unexported field error, got %v", err)
+	}
+}
+
+func TestImportPath(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "models")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n\ngo 1.18\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for dir, want := range map[string]string{root: "example.com/app", dir: "example.com/app/models"} {
+		if path, err := importPath(dir); err != nil || path != want {
+			t.Errorf("%s: expected %s, got %q (%v)", dir, want, path, err)
+		}
+	}
+}
+
+func TestInterfaceAssertion(t *testing.T) {
+	const src = `package main
+
+type user struct {
+	ID   int64  ` + "`" + `sql:"id" key:"true" table:"users"` + "`" + `
+	Name string ` + "`" + `sql:"name"` + "`" + `
+}
+`
+	if err := checkGenerated(t, src, "user", "TableName() string\nKey() int64"); err != nil {
+		t.Fatalf("expected generated code to satisfy DBObject: %v", err)
+	}
+	err := checkGenerated(t, src, "user", "TableName() string\nMissing()")
+	if err == nil || !strings.Contains(err.Error(), "Missing") {
+		t.Fatalf("expected generated code to fail the DBObject assertion, got %v", err)
+	}
+}
+
+func TestKeyValues(t *testing.T) {
+	const src = `package main
+
+type member struct {
+	GroupID int64  ` + "`" + `sql:"group_id" key:"true" table:"members"` + "`" + `
+	UserID  int64  ` + "`" + `sql:"user_id" key:"true"` + "`" + `
+	Role    string ` + "`" + `sql:"role"` + "`" + `
+}
+
+type code struct {
+	Code string ` + "`" + `sql:"code" key:"true" table:"codes"` + "`" + `
+	Name string ` + "`" + `sql:"name"` + "`" + `
+}
+`
+	const iface = "KeyFields() []string\nKeyValues() []interface{}\nKey() int64"
+	for typeName, want := range map[string][]string{
+		"member": {`return []string{"group_id", "user_id"}`, `return []interface{}{o.GroupID, o.UserID}`, `"group_id,user_id,role"`},
+		"code":   {`return []string{"code"}`, `return []interface{}{o.Code}`, "return false", "return 0"},
+	} {
+		out := generateSource(t, src, typeName)
+		for _, w := range want {
+			if !strings.Contains(out, w) {
+				t.Errorf("%s: generated code missing %q:\n%s", typeName, w, out)
+			}
+		}
+		if err := checkGenerated(t, src, typeName, iface); err != nil {
+			t.Errorf("%s: expected generated code to compile: %v", typeName, err)
+		}
+	}
+}
+
+func TestSchemaQuery(t *testing.T) {
+	const src = `package main
+
+type widget struct {
+	ID    int64  ` + "`" + `sql:"id" key:"true" table:"widgets"` + "`" + `
+	Count int    ` + "`" + `sql:"count" default:"0"` + "`" + `
+	Label string ` + "`" + `sql:"label" default:"it's new" notnull:"true"` + "`" + `
+	Size  string ` + "`" + `sql:"size" default:"(lower('M'))"` + "`" + `
+}
+`
+	const want = "create table if not exists widgets (id integer not null primary key, count integer default 0, " +
+		"label text not null default 'it''s new', size text default (lower('M')))"
+	if out := generateSource(t, src, "widget"); !strings.Contains(out, strconv.Quote(want)) {
+		t.Errorf("generated code missing %q:\n%s", want, out)
+	}
+
+	db, err := dbobj.NewDBU(":memory:", true, sqlite.Open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, _, err = db.Exec((&testStruct{}).SchemaQuery()); err != nil {
+		t.Fatal(err)
+	}
+	_, id, err := db.Exec("insert into teststruct (name) values (?)", "schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &testStruct{}
+	if err := db.FindByID(got, id); err != nil {
+		t.Fatal(err)
+	}
+	if got.Created.IsZero() {
+		t.Error("expected created to default to the current timestamp")
+	}
+}
+
+func TestSensitive(t *testing.T) {
+	const src = `package main
+
+type account struct {
+	ID    int64  ` + "`" + `sql:"id" key:"true" table:"accounts"` + "`" + `
+	Name  string ` + "`" + `sql:"name"` + "`" + `
+	Token string ` + "`" + `sql:"token" sensitive:"true"` + "`" + `
+}
+`
+	*stringer = true
+	defer func() { *stringer = false }()
+	out := generateSource(t, src, "account")
+	for _, want := range []string{
+		`return []string{"token"}`,
+		`o.ID, o.Name, "***")`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("generated code missing %q:\n%s", want, out)
+		}
+	}
+	if out := generateSource(t, keylessSource, "keyless"); strings.Contains(out, "Sensitive()") {
+		t.Errorf("expected no Sensitive method without sensitive fields:\n%s", out)
+	}
+}
+
+func TestTableInfo(t *testing.T) {
+	info := (&testStruct{}).TableInfo()
+	if info.Table != "teststruct" || info.Key != "id" {
+		t.Errorf("expected table teststruct keyed by id, got %s keyed by %s", info.Table, info.Key)
+	}
+	want := []dbobj.FieldMeta{
+		{GoName: "ID", SQLName: "id", GoType: "int64"},
+		{GoName: "Name", SQLName: "name", GoType: "string"},
+		{GoName: "Kind", SQLName: "kind", GoType: "testKind"},
+		{GoName: "Data", SQLName: "data", GoType: "[]byte"},
+		{GoName: "Created", SQLName: "created", GoType: "time.Time"},
+	}
+	if len(info.Fields) != len(want) {
+		t.Fatalf("expected %d fields, got %d: %v", len(want), len(info.Fields), info.Fields)
+	}
+	for i, f := range want {
+		if info.Fields[i] != f {
+			t.Errorf("field %d: expected %+v, got %+v", i, f, info.Fields[i])
+		}
+	}
+}
+
+func TestColumns(t *testing.T) {
+	o := &testStruct{}
+	want := []string{"id", "name", "kind", "data", "created"}
+	got := o.Columns()
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected columns %v, got %v", want, got)
+	}
+	if strings.Join(got, ",") != o.SelectFields() {
+		t.Errorf("expected columns to match select fields %q, got %v", o.SelectFields(), got)
+	}
+}
+
+func TestGenericStruct(t *testing.T) {
+	const src = `package main
+
+type Wrapper[T any] struct {
+	ID    int64 ` + "`" + `sql:"id" key:"true" table:"wrappers"` + "`" + `
+	Value T     ` + "`" + `sql:"value"` + "`" + `
+}
+
+type plain struct {
+	ID   int64  ` + "`" + `sql:"id" key:"true" table:"plain"` + "`" + `
+	Name string ` + "`" + `sql:"name"` + "`" + `
+}
+`
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	out := generateSource(t, src, "")
+	if strings.Contains(out, "Wrapper") {
+		t.Errorf("expected generic type to be skipped:\n%s", out)
+	}
+	if !strings.Contains(out, "func (o *plain) TableName() string") {
+		t.Errorf("expected plain type to be generated:\n%s", out)
+	}
+	if !strings.Contains(buf.String(), "skipping generic type Wrapper") {
+		t.Errorf("expected warning about generic type, got %q", buf.String())
+	}
+}
+
+func TestUpdateFieldQuery(t *testing.T) {
+	o := &testStruct{ID: 3, Name: "patch", Kind: 4}
+	query, args, err := o.UpdateFieldQuery(dbobj.SQLite, "teststruct", "name", "kind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "update teststruct set name=?,kind=? where id=?"; query != want {
+		t.Errorf("expected %q, got %q", want, query)
+	}
+	query, _, _ = o.UpdateFieldQuery(dbobj.Postgres, "teststruct", "name", "kind")
+	if want := "update teststruct set name=$1,kind=$2 where id=$3"; query != want {
+		t.Errorf("expected %q, got %q", want, query)
+	}
+	if len(args) != 3 || args[0] != "patch" || args[1] != testKind(4) || args[2] != int64(3) {
+		t.Errorf("unexpected args: %v", args)
+	}
+	for _, col := range []string{"bogus", "created"} {
+		if _, _, err := o.UpdateFieldQuery(dbobj.SQLite, "teststruct", col); !errors.Is(err, dbobj.ErrUnknownColumn) {
+			t.Errorf("%s: expected ErrUnknownColumn, got %v", col, err)
+		}
+	}
+}
+
+func TestReservedNames(t *testing.T) {
+	db, err := dbobj.NewDBU(":memory:", true, sqlite.Open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, _, err = db.Exec((&groupStruct{}).SchemaQuery()); err != nil {
+		t.Fatal(err)
+	}
+	o := &groupStruct{Order: 1, Select: "first"}
+	if err := db.Add(o); err != nil {
+		t.Fatal(err)
+	}
+	query, _, _ := o.UpdateFieldQuery(dbobj.MySQL, o.TableName(), "order")
+	if want := "update `group` set `order`=? where id=?"; query != want {
+		t.Errorf("expected %q, got %q", want, query)
+	}
+	o.Order = 2
+	if err := db.SaveFields(o, "order"); err != nil {
+		t.Fatal(err)
+	}
+	got := &groupStruct{}
+	if err := db.FindByID(got, o.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got.Order != 2 {
+		t.Errorf("expected order 2, got %+v", got)
+	}
+	if err := db.UpsertBy(&groupStruct{Order: 3, Select: "first"}, "select"); err != nil {
+		t.Fatal(err)
+	}
+	var order int
+	query = "select " + got.QualifiedSelectFields() + " from " + strconv.Quote(got.TableName())
+	if err := db.QueryStruct(got, query); err != nil {
+		t.Fatal(err)
+	}
+	if got.Order != 3 || got.Select != "first" {
+		t.Errorf("expected the upsert to update order, got %+v", got)
+	}
+	if err := db.ScanValue(&order, "select count(*) from \"group\""); err != nil || order != 1 {
+		t.Errorf("expected 1 row after the upsert, got %d (%v)", order, err)
+	}
+}
+
+// TestReservedWords confirms the generator quotes the words dbobj does
+func TestReservedWords(t *testing.T) {
+	for word := range reserved {
+		if got, want := quote(word), dbobj.SQLite.Quote(word); got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+	}
+}
+
+func TestBlobString(t *testing.T) {
+	const src = `package main
+
+type doc struct {
+	ID   int64  ` + "`" + `sql:"id" key:"true" table:"docs"` + "`" + `
+	Body string ` + "`" + `sql:"body" blob:"true"` + "`" + `
+	Raw  []byte ` + "`" + `sql:"raw" blob:"true"` + "`" + `
+}
+`
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	out := generateSource(t, src, "doc")
+	for _, want := range []string{
+		"return []interface{}{dbobj.Blob(o.Body), o.Raw}",
+		"return []interface{}{&o.ID, (*dbobj.Blob)(&o.Body), &o.Raw}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("generated code missing %q:\n%s", want, out)
+		}
+	}
+	if !strings.Contains(buf.String(), "field Body is a string blob") {
+		t.Errorf("expected note recommending []byte, got: %q", buf.String())
+	}
+}
+
+func TestBlobRoundTrip(t *testing.T) {
+	db, err := dbobj.NewDBU(":memory:", true, sqlite.Open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, _, err = db.Exec(testSchema); err != nil {
+		t.Fatal(err)
+	}
+	data := []byte{0xff, 0xfe, 0x00, 0x80, 'a', 0xc3}
+	o := &testStruct{Name: "binary", Data: data}
+	if err := db.Add(o); err != nil {
+		t.Fatal(err)
+	}
+	got := &testStruct{}
+	if err := db.FindByID(got, o.ID); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Data, data) {
+		t.Errorf("expected %x, got %x", data, got.Data)
+	}
+}
+
+func TestRepo(t *testing.T) {
+	db, err := dbobj.NewDBU(":memory:", true, sqlite.Open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, _, err = db.Exec(testSchema); err != nil {
+		t.Fatal(err)
+	}
+	repo := dbobj.NewRepo[testStruct](db)
+	o := &testStruct{Name: "generated", Kind: 2}
+	if err := repo.Create(o); err != nil {
+		t.Fatal(err)
+	}
+	got, err := repo.Get(o.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != o.Name || got.Kind != o.Kind {
+		t.Errorf("expected %+v, got %+v", o, got)
+	}
+}
+
+func TestFormatError(t *testing.T) {
+	var g Generator
+	g.Printf("package main\n\nfunc ok() {}\n\n")
+	g.Printf("func (o *broken) Key() int64 {\n\treturn o.ID +\n}\n")
+	src, err := g.format()
+	if err == nil {
+		t.Fatal("expected error formatting invalid Go")
+	}
+	if !bytes.Equal(src, g.buf.Bytes()) {
+		t.Error("expected unformatted source to be returned")
+	}
+	for _, want := range []string{
+		"invalid Go generated: 7:1",
+		">    7: }",
+		"     6: \treturn o.ID +",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("diagnostic missing %q:\n%s", want, err)
+		}
+	}
+}
+
+func TestSplitRoundTrip(t *testing.T) {
+	db, err := dbobj.NewDBU(":memory:", true, sqlite.Open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, _, err = db.Exec(listSchema); err != nil {
+		t.Fatal(err)
+	}
+	o := &listStruct{Tags: []string{"a", "b", "c"}}
+	if err := db.Add(o); err != nil {
+		t.Fatal(err)
+	}
+	var raw string
+	if err := db.DB().QueryRow("select tags from lists where id=?", o.ID).Scan(&raw); err != nil {
+		t.Fatal(err)
+	}
+	if raw != "a,b,c" {
+		t.Errorf("expected column to store %q, got %q", "a,b,c", raw)
+	}
+	got := &listStruct{}
+	if err := db.FindByID(got, o.ID); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(o) {
+		t.Errorf("expected %v, got %v", o.Tags, got.Tags)
+	}
+	empty := &listStruct{}
+	if err := db.Add(empty); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.FindByID(got, empty.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got.Tags == nil || len(got.Tags) != 0 {
+		t.Errorf("expected an empty slice, got %#v", got.Tags)
+	}
+}
+
+func TestFromRow(t *testing.T) {
+	db, err := dbobj.NewDBU(":memory:", true, sqlite.Open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, schema := range []string{testSchema, listSchema} {
+		if _, _, err = db.Exec(schema); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"tagged", "untagged", "also tagged"} {
+		o := &testStruct{Name: name}
+		if err := db.Add(o); err != nil {
+			t.Fatal(err)
+		}
+		if name != "untagged" {
+			if _, _, err := db.Exec("insert into lists (id, tags) values(?, 'x')", o.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	rows, err := db.DB().Query("select " + (&testStruct{}).QualifiedSelectFields() +
+		" from teststruct join lists on lists.id = teststruct.id order by teststruct.id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		o := (&testStruct{}).NewObj().(*testStruct)
+		if err := o.FromRow(rows); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, o.Name)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(names, ",") != "tagged,also tagged" {
+		t.Errorf("unexpected objects: %v", names)
+	}
+}
+
+func TestOmitEmpty(t *testing.T) {
+	const src = `package main
+
+type widget struct {
+	ID   int64  ` + "`" + `sql:"id" key:"true" table:"widgets"` + "`" + `
+	Name string ` + "`" + `sql:"name"` + "`" + `
+	Kind int    ` + "`" + `sql:"kind" omitempty:"true"` + "`" + `
+}
+`
+	out := generateSource(t, src, "widget")
+	for _, want := range []string{
+		"InsertValues() []interface{} {\n\treturn []interface{}{o.Name, dbobj.OmitEmpty(o.Kind)}",
+		"UpdateValues() []interface{} {\n\treturn []interface{}{o.Name, o.Kind, o.ID}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("generated code missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	const src = `package main
+
+type user struct {
+	ID   int64  ` + "`" + `sql:"id" key:"true" table:"users"` + "`" + `
+	Name string ` + "`" + `sql:"name"` + "`" + `
+}
+
+type group struct {
+	ID   int64  ` + "`" + `sql:"id" key:"true" table:"groups"` + "`" + `
+	Name string ` + "`" + `sql:"name"` + "`" + `
+}
+`
+	var g Generator
+	if err := g.parsePackage(".", []string{"source.go"}, src); err != nil {
+		t.Fatal(err)
+	}
+	g.header("test")
+	for _, typeName := range []string{"user", "group"} {
+		if err := g.generate(typeName); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g.buildRegistry()
+	out, err := g.format()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `var AllObjects = []func() dbobj.DBObject{
+	func() dbobj.DBObject { return new(user) },
+	func() dbobj.DBObject { return new(group) },
+}`
+	if !strings.Contains(string(out), want) {
+		t.Errorf("generated code missing registry:\n%s", out)
+	}
+	tables := make(map[string]bool)
+	for _, fn := range AllObjects {
+		tables[fn().TableName()] = true
+	}
+	if len(tables) != len(AllObjects) || !tables["teststruct"] || !tables["audits"] {
+		t.Errorf("expected distinct tables for each registered type, got %v", tables)
+	}
+}
+
+// returned returns the expressions returned by the method of the receiver type
+func returned(t *testing.T, fset *token.FileSet, f *ast.File, typeName, method string) []ast.Expr {
+	t.Helper()
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv == nil || fn.Name.Name != method {
+			continue
+		}
+		var buf bytes.Buffer
+		printer.Fprint(&buf, fset, fn.Recv.List[0].Type)
+		if buf.String() != "*"+typeName {
+			continue
+		}
+		ret := fn.Body.List[len(fn.Body.List)-1].(*ast.ReturnStmt)
+		return ret.Results
+	}
+	t.Fatalf("no %s method for %s", method, typeName)
+	return nil
+}
+
+func TestSelectFieldsMatchPointers(t *testing.T) {
+	out := generateFiles(t, "", "struct_test.go")
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "generated.go", out, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := func(x ast.Node) string {
+		var buf bytes.Buffer
+		printer.Fprint(&buf, fset, x)
+		return buf.String()
+	}
+	for _, typeName := range []string{"testStruct", "auditStruct", "listStruct", "stampStruct", "userStruct", "rowidStruct", "groupStruct"} {
+		lit := returned(t, fset, f, typeName, "SelectFields")[0].(*ast.BasicLit)
+		fields, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		columns := strings.Split(fields, ",")
+		pointers := returned(t, fset, f, typeName, "MemberPointers")[0].(*ast.CompositeLit).Elts
+		if len(columns) != len(pointers) {
+			t.Fatalf("%s: %d select fields but %d member pointers", typeName, len(columns), len(pointers))
+		}
+		// FieldMap pairs each column with its pointer independently of order
+		byColumn := make(map[string]string)
+		for _, elt := range returned(t, fset, f, typeName, "FieldMap")[0].(*ast.CompositeLit).Elts {
+			kv := elt.(*ast.KeyValueExpr)
+			column, _ := strconv.Unquote(kv.Key.(*ast.BasicLit).Value)
+			byColumn[column] = source(kv.Value)
+		}
+		for i, column := range columns {
+			if got := source(pointers[i]); got != byColumn[column] {
+				t.Errorf("%s: select field %d %q scans into %s, expected %s", typeName, i, column, got, byColumn[column])
+			}
+		}
+	}
+}
@@ -6,15 +6,64 @@ import (
 
 type testStruct struct {
 	ID      int64     `sql:"id" key:"true" table:"teststruct"`
-	Name    string    `sql:"name"`
-	Kind    int       `sql:"kind"`
+	Name    string    `sql:"name" maxlen:"255"`
+	Kind    testKind  `sql:"kind" enum:"testKind" min:"0"`
 	Data    []byte    `sql:"data"`
-	Created time.Time `sql:"created" update:"false" audit:"time"`
+	Created time.Time `sql:"created" update:"false" audit:"time" default:"CURRENT_TIMESTAMP"`
+}
+
+// auditStruct uses pointers to distinguish never modified from zero values
+type auditStruct struct {
+	ID       int64      `sql:"id" key:"true" table:"audits"`
+	UserID   *int64     `sql:"userid" audit:"user"`
+	Modified *time.Time `sql:"modified" audit:"time"`
+}
+
+// listStruct stores its tags as a delimited string
+type listStruct struct {
+	ID   int64    `sql:"id" key:"true" table:"lists"`
+	Tags []string `sql:"tags" split:","`
+}
+
+// stampStruct records who created and last updated it, and when
+type stampStruct struct {
+	ID      int64     `sql:"id" key:"true" table:"stamps"`
+	Name    string    `sql:"name"`
+	Creator int64     `sql:"created_by" audit:"create_user" update:"false"`
+	Created time.Time `sql:"created_at" audit:"create_time" update:"false"`
+	Updater int64     `sql:"updated_by" audit:"update_user"`
+	Updated time.Time `sql:"updated_at" audit:"update_time"`
+}
+
+// userStruct has a surrogate key and a natural key
+type userStruct struct {
+	ID    int64  `sql:"id" key:"true" table:"users"`
+	Email string `sql:"email" unique:"email"`
+	Name  string `sql:"name"`
+}
+
+// rowidStruct is keyed by the implicit rowid
+type rowidStruct struct {
+	ID   int64  `rowid:"true" table:"notes"`
+	Note string `sql:"note"`
+}
+
+// groupStruct has a table and a column named for reserved words
+type groupStruct struct {
+	ID     int64  `sql:"id" key:"true" table:"group"`
+	Order  int    `sql:"order"`
+	Select string `sql:"select" unique:"select"`
 }
 
 // make lint happy, it can't otherwise detect its use
 // but that's in generated output
 var _ = testStruct{}
+var _ = auditStruct{}
+var _ = listStruct{}
+var _ = stampStruct{}
+var _ = userStruct{}
+var _ = rowidStruct{}
+var _ = groupStruct{}
 
 const testSchema = `create table teststruct (
 	id integer not null primary key,
@@ -23,3 +72,18 @@ const testSchema = `create table teststruct (
 	data blob,
 	created     DATETIME DEFAULT CURRENT_TIMESTAMP
 );`
+
+const userSchema = `create table users (
+	id integer not null primary key,
+	email text not null unique,
+	name text
+);`
+
+const rowidSchema = `create table notes (
+	note text
+);`
+
+const listSchema = `create table lists (
+	id integer not null primary key,
+	tags text
+);`
@@ -0,0 +1,206 @@
+package dbobj
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Dialect identifies the SQL variant spoken by the database
+type Dialect int
+
+const (
+	// SQLite is the default dialect
+	SQLite Dialect = iota
+	// Postgres is PostgreSQL
+	Postgres
+	// MySQL is MySQL or MariaDB
+	MySQL
+)
+
+func (d Dialect) String() string {
+	switch d {
+	case SQLite:
+		return "sqlite"
+	case Postgres:
+		return "postgres"
+	case MySQL:
+		return "mysql"
+	}
+	return "unknown"
+}
+
+// serialized reports whether the database only allows a single writer,
+// in which case writes are serialized by the DBU rather than contending for the database
+func (d Dialect) serialized() bool {
+	return d == SQLite
+}
+
+// Placeholder returns the bind parameter for the i'th argument of a statement, counting from 1
+func (d Dialect) Placeholder(i int) string {
+	if d == Postgres {
+		return "$" + strconv.Itoa(i)
+	}
+	return "?"
+}
+
+// Placeholders returns n comma separated bind parameters, numbered from start
+// so statements combining several segments, e.g., an upsert, number contiguously
+func (d Dialect) Placeholders(start, n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(d.Placeholder(start + i))
+	}
+	return b.String()
+}
+
+// reserved holds common SQL keywords, which must be quoted when used as identifiers
+var reserved = map[string]bool{
+	"add": true, "all": true, "alter": true, "and": true, "as": true, "asc": true,
+	"between": true, "by": true, "case": true, "check": true, "column": true,
+	"constraint": true, "create": true, "cross": true, "default": true, "delete": true,
+	"desc": true, "distinct": true, "drop": true, "else": true, "end": true,
+	"exists": true, "foreign": true, "from": true, "full": true, "group": true,
+	"having": true, "in": true, "index": true, "inner": true, "insert": true,
+	"into": true, "is": true, "join": true, "key": true, "left": true, "like": true,
+	"limit": true, "not": true, "null": true, "offset": true, "on": true, "or": true,
+	"order": true, "outer": true, "primary": true, "references": true, "right": true,
+	"select": true, "set": true, "table": true, "then": true, "to": true,
+	"union": true, "unique": true, "update": true, "user": true, "using": true,
+	"values": true, "when": true, "where": true, "with": true,
+}
+
+// Quote returns the identifier quoted for the dialect if it is a reserved word,
+// e.g., a column named order, otherwise the identifier as is
+func (d Dialect) Quote(name string) string {
+	name = strings.TrimSpace(name)
+	if !reserved[strings.ToLower(name)] {
+		return name
+	}
+	if d == MySQL {
+		return "`" + name + "`"
+	}
+	return `"` + name + `"`
+}
+
+// quoteAll returns the identifiers quoted for the dialect
+func (d Dialect) quoteAll(names []string) []string {
+	list := make([]string, len(names))
+	for i, name := range names {
+		list[i] = d.Quote(name)
+	}
+	return list
+}
+
+// selectFields returns the object's select fields with reserved words quoted
+func (d Dialect) selectFields(o DBObject) string {
+	fields := o.SelectFields()
+	for _, field := range strings.Split(fields, ",") {
+		if reserved[strings.ToLower(strings.TrimSpace(field))] {
+			return strings.Join(d.quoteAll(strings.Split(fields, ",")), ",")
+		}
+	}
+	return fields
+}
+
+// SetDialect sets the SQL dialect of the database
+func (du *DBU) SetDialect(d Dialect) {
+	du.dialect = d
+}
+
+// Dialect returns the SQL dialect of the database
+func (du *DBU) Dialect() Dialect {
+	return du.dialect
+}
+
+// lockWrites serializes writes for dialects that only allow a single writer,
+// returning the function to release the lock
+func (du *DBU) lockWrites() func() {
+	if !du.dialect.serialized() {
+		return func() {}
+	}
+	du.mu.Lock()
+	return du.mu.Unlock
+}
+
+// keyLocks is a striped set of mutexes, so writes to different keys rarely contend
+type keyLocks struct {
+	stripes []sync.Mutex
+}
+
+func newKeyLocks(n int) *keyLocks {
+	return &keyLocks{stripes: make([]sync.Mutex, n)}
+}
+
+// index returns the stripe of the table's key
+func (k *keyLocks) index(table string, key int64) int {
+	h := fnv.New32a()
+	h.Write([]byte(table))
+	return int((uint64(h.Sum32()) + uint64(key)) % uint64(len(k.stripes)))
+}
+
+// lock locks the stripe for the table's key, returning the function to unlock it
+func (k *keyLocks) lock(table string, key int64) func() {
+	stripe := &k.stripes[k.index(table, key)]
+	stripe.Lock()
+	return stripe.Unlock
+}
+
+// lockAll locks the distinct stripes for the table's keys in order, so that
+// concurrent callers can't deadlock, returning the function to unlock them
+func (k *keyLocks) lockAll(table string, keys []int64) func() {
+	seen := make(map[int]bool)
+	var list []int
+	for _, key := range keys {
+		if i := k.index(table, key); !seen[i] {
+			seen[i] = true
+			list = append(list, i)
+		}
+	}
+	sort.Ints(list)
+	for _, i := range list {
+		k.stripes[i].Lock()
+	}
+	return func() {
+		for _, i := range list {
+			k.stripes[i].Unlock()
+		}
+	}
+}
+
+// SetKeyLocks enables n striped per-key locks, so that concurrent writes to the same
+// object are serialized on dialects that don't serialize all writes. Zero disables them
+func (du *DBU) SetKeyLocks(n int) {
+	if n <= 0 {
+		du.keyLocks = nil
+		return
+	}
+	du.keyLocks = newKeyLocks(n)
+}
+
+// lockKey locks the object's key stripe when key locks are in use,
+// returning the function to unlock it
+func (du *DBU) lockKey(o DBObject) func() {
+	if du.keyLocks == nil || du.dialect.serialized() {
+		return func() {}
+	}
+	return du.keyLocks.lock(tableName(o), o.Key())
+}
+
+// lockKeys locks the key stripes of objects of the same table when key locks
+// are in use, returning the function to unlock them
+func (du *DBU) lockKeys(objs []DBObject) func() {
+	if du.keyLocks == nil || du.dialect.serialized() || len(objs) == 0 {
+		return func() {}
+	}
+	keys := make([]int64, len(objs))
+	for i, o := range objs {
+		keys[i] = o.Key()
+	}
+	return du.keyLocks.lockAll(tableName(objs[0]), keys)
+}
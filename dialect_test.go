@@ -0,0 +1,43 @@
+package dbobj
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlaceholders(t *testing.T) {
+	if got := Placeholders(3); got != "?,?,?" {
+		t.Errorf("expected ?,?,?, got %q", got)
+	}
+	if got := SQLite.Placeholders(4, 2); got != "?,?" {
+		t.Errorf("expected SQLite to ignore the offset, got %q", got)
+	}
+	if got := Postgres.Placeholders(1, 0); got != "" {
+		t.Errorf("expected no placeholders, got %q", got)
+	}
+}
+
+func TestPostgresPlaceholders(t *testing.T) {
+	s := &testStruct{ID: 3, Name: "pg"}
+	if got, _ := insertQuery(s, Postgres); got != "insert into structs (name,kind,data) values($1,$2,$3)" {
+		t.Errorf("unexpected insert: %q", got)
+	}
+	if got := updateQuery(s, Postgres); got != "update structs set name=$1,kind=$2,data=$3 where id=$4" {
+		t.Errorf("unexpected update: %q", got)
+	}
+	if got, args := replaceQuery(s, Postgres); got != "replace into structs (id,name,kind,data) values($1,$2,$3,$4)" || len(args) != 4 {
+		t.Errorf("unexpected replace: %q %v", got, args)
+	}
+	if got, _ := deleteKeyQuery(s, Postgres); got != "delete from structs where id=$1" {
+		t.Errorf("unexpected delete: %q", got)
+	}
+	// a where clause following another arg continues its numbering
+	clause, args := NewWhere().Eq("name", "pg").In("id", 1, 2).DialectClause(Postgres, 2)
+	if clause != "name=$2 and id in ($3,$4)" || len(args) != 3 {
+		t.Errorf("unexpected clause: %q %v", clause, args)
+	}
+	query, args := expandArgs(Postgres, "id in ($1) and kind=$2 and x='$1'", []interface{}{[]int{1, 2, 3}, 4})
+	if query != "id in ($1,$2,$3) and kind=$4 and x='$1'" || !reflect.DeepEqual(args, []interface{}{1, 2, 3, 4}) {
+		t.Errorf("unexpected expansion: %q %v", query, args)
+	}
+}
@@ -0,0 +1,124 @@
+package dbobj
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// noIDDriver is a driver whose results can't report the last insert id,
+// with an optional delay on each exec to mimic a networked database
+type noIDDriver struct {
+	delay time.Duration
+}
+
+type noIDConn struct {
+	delay time.Duration
+}
+
+type noIDStmt struct {
+	delay time.Duration
+}
+
+type noIDResult struct{}
+
+func init() {
+	sql.Register("noid", noIDDriver{})
+	sql.Register("slow", noIDDriver{delay: 100 * time.Microsecond})
+}
+
+func (d noIDDriver) Open(name string) (driver.Conn, error) { return noIDConn{d.delay}, nil }
+
+func (c noIDConn) Prepare(query string) (driver.Stmt, error) { return noIDStmt{c.delay}, nil }
+func (noIDConn) Close() error                                { return nil }
+func (noIDConn) Begin() (driver.Tx, error)                   { return nil, errors.New("not supported") }
+
+func (noIDStmt) Close() error  { return nil }
+func (noIDStmt) NumInput() int { return -1 }
+func (s noIDStmt) Exec(args []driver.Value) (driver.Result, error) {
+	time.Sleep(s.delay)
+	return noIDResult{}, nil
+}
+func (noIDStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func (noIDResult) LastInsertId() (int64, error) { return 0, errors.New("last insert id not supported") }
+func (noIDResult) RowsAffected() (int64, error) { return 1, nil }
+
+func TestAddWithoutLastInsertID(t *testing.T) {
+	db, err := sql.Open("noid", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	du := &DBU{db: db}
+	s := &testStruct{ID: 42, Name: "preset"}
+	if err := du.Add(s); err != nil {
+		t.Fatal(err)
+	}
+	if s.ID != 42 {
+		t.Errorf("expected preset key 42 to be preserved, got %d", s.ID)
+	}
+}
+
+func TestKeyLocks(t *testing.T) {
+	locks := newKeyLocks(4)
+	unlock := locks.lock("structs", 1)
+	done := make(chan struct{})
+	go func() {
+		// a different stripe doesn't wait
+		locks.lock("structs", 2)()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("distinct keys contended")
+	}
+	unlock()
+
+	du := &DBU{dialect: SQLite}
+	du.SetKeyLocks(4)
+	du.lockKey(&testStruct{ID: 1})()
+	if du.Dialect().String() != "sqlite" {
+		t.Errorf("unexpected dialect: %v", du.Dialect())
+	}
+}
+
+func benchmarkSave(b *testing.B, d Dialect, stripes int) {
+	db, err := sql.Open("slow", "")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(64)
+	du := &DBU{db: db}
+	du.SetDialect(d)
+	du.SetKeyLocks(stripes)
+	var id int64
+	b.SetParallelism(8)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		s := &testStruct{ID: atomic.AddInt64(&id, 1), Name: "bench"}
+		for pb.Next() {
+			if err := du.Save(s); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkSaveSerialized writes through the global lock, as for SQLite
+func BenchmarkSaveSerialized(b *testing.B) {
+	benchmarkSave(b, SQLite, 0)
+}
+
+// BenchmarkSaveKeyLocks writes to distinct keys through striped key locks
+func BenchmarkSaveKeyLocks(b *testing.B) {
+	benchmarkSave(b, Postgres, 64)
+}
@@ -1,9 +1,14 @@
 module github.com/paulstuart/dbobj
 
-go 1.13
+go 1.18
 
 require (
 	github.com/paulstuart/sqlite v0.0.1
 	github.com/pkg/errors v0.9.1
 	github.com/rqlite/gorqlite v0.0.0-20200618114933-40a3fff2a017
 )
+
+require (
+	github.com/mattn/go-sqlite3 v1.14.0 // indirect
+	github.com/paulstuart/dbutil v0.0.1 // indirect
+)
@@ -0,0 +1,142 @@
+package dbobj
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Iter is a pull iterator over the results of a query
+type Iter struct {
+	rows   *sql.Rows
+	cancel context.CancelFunc
+	query  string
+	cols   int
+	closed bool
+
+	// rows read in full from backends that can't stream them, e.g., rqlite
+	buffered []DBObject
+	current  DBObject
+}
+
+// rowStreamer is implemented by backends able to stream the rows of a query
+type rowStreamer interface {
+	rows(query string, args ...interface{}) (*sql.Rows, context.CancelFunc, error)
+}
+
+// rows returns the rows of the query, which remain subject to the timeout
+// until the returned function is called
+func (s sqlWrapper) rows(query string, args ...interface{}) (*sql.Rows, context.CancelFunc, error) {
+	if s.db == nil {
+		return nil, nil, ErrClosed
+	}
+	ctx, cancel := s.context()
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	return rows, cancel, nil
+}
+
+// Iterate returns an iterator over the objects matching the optional where clause.
+// Rows are streamed from a sql.DB, while other backends are read in full.
+// The caller must Close the iterator when done
+func (du *DBU) Iterate(o DBObject, where string, args ...interface{}) (*Iter, error) {
+	query := fmt.Sprintf("select %s from %s", du.dialect.selectFields(o), du.dialect.Quote(tableName(o)))
+	if where != "" {
+		query += " where " + where
+	}
+	query, args = expandArgs(du.dialect, query, args)
+	du.debugf("Q: %s A:%v\n", query, args)
+	s, ok := du.backend().(rowStreamer)
+	if !ok {
+		it := &Iter{query: query}
+		fn := func() []interface{} {
+			item := newObject(o)
+			it.buffered = append(it.buffered, item)
+			return item.MemberPointers()
+		}
+		if err := du.Query(fn, query, args...); err != nil {
+			return nil, err
+		}
+		return it, nil
+	}
+	return du.stream(s, query, args...)
+}
+
+// stream opens the rows of the iterator, recording the metrics of the query
+func (du *DBU) stream(s rowStreamer, query string, args ...interface{}) (it *Iter, err error) {
+	if du.metrics != nil {
+		defer du.measure("query", query, time.Now(), &err)
+	}
+	rows, cancel, err := s.rows(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	cols, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		cancel()
+		return nil, err
+	}
+	return &Iter{rows: rows, cancel: cancel, query: query, cols: len(cols)}, nil
+}
+
+// Next prepares the next row for Scan, returning false when there are no more
+func (it *Iter) Next() bool {
+	if it.closed {
+		return false
+	}
+	if it.rows == nil {
+		if len(it.buffered) == 0 {
+			return false
+		}
+		it.current, it.buffered = it.buffered[0], it.buffered[1:]
+		return true
+	}
+	return it.rows.Next()
+}
+
+// Scan loads the current row into the object
+func (it *Iter) Scan(o DBObject) error {
+	dest := o.MemberPointers()
+	if it.rows == nil {
+		src := it.current.MemberPointers()
+		if err := columnCheck(it.query, len(src), len(dest)); err != nil {
+			return err
+		}
+		for i, ptr := range dest {
+			reflect.ValueOf(ptr).Elem().Set(reflect.ValueOf(src[i]).Elem())
+		}
+		return nil
+	}
+	if err := columnCheck(it.query, it.cols, len(dest)); err != nil {
+		return err
+	}
+	return it.rows.Scan(dest...)
+}
+
+// Err returns the error, if any, encountered during iteration
+func (it *Iter) Err() error {
+	if it.rows == nil {
+		return nil
+	}
+	return it.rows.Err()
+}
+
+// Close releases the iterator's resources, and is safe to call more than once
+func (it *Iter) Close() error {
+	if it.closed {
+		return nil
+	}
+	it.closed = true
+	if it.rows == nil {
+		it.buffered, it.current = nil, nil
+		return nil
+	}
+	defer it.cancel()
+	return it.rows.Close()
+}
@@ -0,0 +1,121 @@
+package dbobj
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIterate(t *testing.T) {
+	db := structDBU(t)
+	it, err := db.Iterate(&testStruct{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for it.Next() {
+		s := testStruct{}
+		if err := it.Scan(&s); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, s.Name)
+		if len(names) == 2 {
+			break
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := it.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := it.Close(); err != nil {
+		t.Fatalf("second close: %v", err)
+	}
+	if it.Next() {
+		t.Error("expected no rows after close")
+	}
+	if len(names) != 2 || names[0] != "abc" || names[1] != "def" {
+		t.Errorf("expected abc and def, got %v", names)
+	}
+
+	// the connection is released once closed
+	if n := countStructs(t, db); n != 6 {
+		t.Errorf("expected 6 records, got %d", n)
+	}
+}
+
+func TestIterateWhere(t *testing.T) {
+	db := structDBU(t)
+	it, err := db.Iterate(&testStruct{}, "kind=?", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+	count := 0
+	for it.Next() {
+		s := testStruct{}
+		if err := it.Scan(&s); err != nil {
+			t.Fatal(err)
+		}
+		if s.Kind != 2 {
+			t.Errorf("expected kind 2, got %+v", s)
+		}
+		count++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 rows, got %d", count)
+	}
+}
+
+func TestIterateMetrics(t *testing.T) {
+	db := structDBU(t)
+	var queries []string
+	db.SetMetrics(func(op, query string, dur time.Duration, err error) {
+		queries = append(queries, op+": "+query)
+	})
+	it, err := db.Iterate(&testStruct{}, "kind=?", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+	if len(queries) != 1 || !strings.HasPrefix(queries[0], "query: select") {
+		t.Errorf("expected the query to be measured, got %v", queries)
+	}
+}
+
+func TestIterateBackend(t *testing.T) {
+	db := structDBU(t)
+	// the DBS interface can't stream rows, so they're read in full
+	du := &DBU{dbs: &recordingDBS{DBS: sqlWrapper{db: db.db}}}
+	it, err := du.Iterate(&testStruct{}, "kind=?", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for it.Next() {
+		s := testStruct{}
+		if err := it.Scan(&s); err != nil {
+			t.Fatal(err)
+		}
+		if s.Kind != 2 {
+			t.Errorf("expected kind 2, got %+v", s)
+		}
+		names = append(names, s.Name)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := it.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 3 {
+		t.Errorf("expected 3 rows, got %v", names)
+	}
+	if it.Next() {
+		t.Error("expected no rows after close")
+	}
+}
@@ -0,0 +1,95 @@
+package dbobj
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// MarshalColumns marshals the values as a JSON object keyed by their sql columns,
+// in column order, for generated MarshalJSON methods
+func MarshalColumns(columns []string, values ...interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, column := range columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(column)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalColumns unmarshals a JSON object keyed by sql columns into the
+// member pointers of fields, for generated UnmarshalJSON methods.
+// Keys that aren't columns are ignored
+func UnmarshalColumns(data []byte, fields map[string]interface{}) error {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	for column, raw := range values {
+		if ptr, ok := fields[column]; ok {
+			if err := json.Unmarshal(raw, ptr); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// StreamJSON writes the objects matching the optional where clause to w as a JSON array,
+// each an object keyed by its sql columns, one row at a time
+func (du *DBU) StreamJSON(w io.Writer, o DBObject, where string, args ...interface{}) error {
+	it, err := du.Iterate(o, where, args...)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+	columns := selectColumns(o)
+	obj := newObject(o)
+	dest := obj.MemberPointers()
+	values := make([]interface{}, len(dest))
+	sep := "["
+	for it.Next() {
+		if err := it.Scan(obj); err != nil {
+			return err
+		}
+		for i, ptr := range dest {
+			if values[i], err = memberValue(ptr); err != nil {
+				return err
+			}
+		}
+		row, err := MarshalColumns(columns, values...)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, sep); err != nil {
+			return err
+		}
+		if _, err := w.Write(row); err != nil {
+			return err
+		}
+		sep = ","
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	if sep == "[" {
+		_, err = io.WriteString(w, "[]")
+		return err
+	}
+	_, err = io.WriteString(w, "]")
+	return err
+}
@@ -0,0 +1,71 @@
+package dbobj
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestMarshalColumns(t *testing.T) {
+	when := time.Date(2020, 2, 3, 4, 5, 6, 0, time.UTC)
+	data, err := MarshalColumns([]string{"id", "name", "modified"}, int64(1), "abc", when)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"id":1,"name":"abc","modified":"2020-02-03T04:05:06Z"}`
+	if string(data) != want {
+		t.Errorf("expected %s, got %s", want, data)
+	}
+	var s testStruct
+	fields := map[string]interface{}{"id": &s.ID, "name": &s.Name, "modified": &s.Modified}
+	if err := UnmarshalColumns([]byte(`{"id":2,"name":"def","modified":"2020-02-03T04:05:06Z","extra":true}`), fields); err != nil {
+		t.Fatal(err)
+	}
+	if s.ID != 2 || s.Name != "def" || !s.Modified.Equal(when) {
+		t.Errorf("unexpected object: %+v", s)
+	}
+	if err := UnmarshalColumns([]byte(`{"id":"two"}`), fields); err == nil {
+		t.Error("expected error for mistyped column")
+	}
+}
+
+func TestStreamJSON(t *testing.T) {
+	db := structDBU(t)
+	list := new(_testStruct)
+	if err := db.ListQueryArgs(list, "kind > ? order by id", 2); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := db.StreamJSON(&buf, &testStruct{}, "kind > ? order by id", 2); err != nil {
+		t.Fatal(err)
+	}
+	type row struct {
+		ID       int64     `json:"id"`
+		Name     string    `json:"name"`
+		Kind     int       `json:"kind"`
+		Data     string    `json:"data"`
+		Modified time.Time `json:"modified"`
+	}
+	var rows []row
+	if err := json.Unmarshal(buf.Bytes(), &rows); err != nil {
+		t.Fatalf("invalid JSON %s: %v", buf.Bytes(), err)
+	}
+	if len(rows) == 0 || len(rows) != len(*list) {
+		t.Fatalf("expected %d rows, got %d", len(*list), len(rows))
+	}
+	for i, s := range *list {
+		r := rows[i]
+		if r.ID != s.ID || r.Name != s.Name || r.Kind != s.Kind || r.Data != s.Data || !r.Modified.Equal(s.Modified) {
+			t.Errorf("row %d: expected %+v, got %+v", i, s, r)
+		}
+	}
+
+	buf.Reset()
+	if err := db.StreamJSON(&buf, &testStruct{}, "kind < ?", -100); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "[]" {
+		t.Errorf("expected an empty array, got %s", buf.String())
+	}
+}
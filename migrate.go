@@ -0,0 +1,117 @@
+package dbobj
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// ErrDuplicateVersion is returned when migrations share a version
+var ErrDuplicateVersion = errors.New("duplicate migration version")
+
+const migrationTable = "schema_migrations"
+
+// Migration is a versioned schema change
+type Migration struct {
+	Version int
+	Up      string
+}
+
+// Migrator applies schema migrations
+type Migrator interface {
+	Migrate(migrations []Migration) error
+}
+
+var _ Migrator = (*DBU)(nil)
+
+// Migrate applies the migrations not yet recorded in the schema_migrations table,
+// in version order, each within its own transaction
+func (du *DBU) Migrate(migrations []Migration) error {
+	if du.readOnly {
+		return ErrReadOnly
+	}
+	if du.db == nil {
+		return du.noTx()
+	}
+	query := "create table if not exists " + migrationTable + ` (
+	version integer not null primary key,
+	applied DATETIME DEFAULT CURRENT_TIMESTAMP
+)`
+	ctx, cancel := sqlWrapper{du.db, du.queryTimeout}.context()
+	defer cancel()
+	if _, err := du.db.ExecContext(ctx, query); err != nil {
+		return err
+	}
+	applied, err := du.appliedVersions(ctx)
+	if err != nil {
+		return err
+	}
+	pending := make([]Migration, len(migrations))
+	copy(pending, migrations)
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].Version < pending[j].Version
+	})
+	for i, m := range pending {
+		if i > 0 && pending[i-1].Version == m.Version {
+			return errors.Wrapf(ErrDuplicateVersion, "version: %d", m.Version)
+		}
+	}
+	for _, m := range pending {
+		if applied[m.Version] {
+			continue
+		}
+		du.debugf("MIGRATE: %d Q: %s\n", m.Version, m.Up)
+		if err := du.migrate(m); err != nil {
+			return errors.Wrapf(err, "migration version: %d", m.Version)
+		}
+	}
+	return nil
+}
+
+// appliedVersions returns the set of versions already migrated
+func (du *DBU) appliedVersions(ctx context.Context) (map[int]bool, error) {
+	rows, err := du.db.QueryContext(ctx, "select version from "+migrationTable)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// migrate applies a single migration and records its version as one transaction
+func (du *DBU) migrate(m Migration) error {
+	du.mu.Lock()
+	defer du.mu.Unlock()
+	ctx, cancel := sqlWrapper{du.db, du.queryTimeout}.context()
+	defer cancel()
+	tx, err := du.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err = migrateTx(ctx, tx, du.dialect, m); err != nil {
+		if e := tx.Rollback(); e != nil && e != sql.ErrTxDone {
+			log.Printf("migrate rollback error: %v\n", e)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+func migrateTx(ctx context.Context, tx *sql.Tx, d Dialect, m Migration) error {
+	if _, err := tx.ExecContext(ctx, m.Up); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, "insert into "+migrationTable+" (version) values("+d.Placeholder(1)+")", m.Version)
+	return err
+}
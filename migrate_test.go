@@ -0,0 +1,84 @@
+package dbobj
+
+import (
+	"testing"
+
+	sqlite "github.com/paulstuart/sqlite"
+	"github.com/pkg/errors"
+)
+
+func migrateDBU(t *testing.T) *DBU {
+	t.Helper()
+	db, err := sqlite.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &DBU{db: db}
+}
+
+func migrationCount(t *testing.T, db *DBU) int {
+	t.Helper()
+	var count int
+	if err := db.DB().QueryRow("select count(*) from schema_migrations").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+var testMigrations = []Migration{
+	{Version: 2, Up: "alter table people add column email text"},
+	{Version: 1, Up: "create table people (id integer primary key, name text)"},
+}
+
+func TestMigrate(t *testing.T) {
+	db := migrateDBU(t)
+	defer db.Close()
+	if err := db.Migrate(testMigrations); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.DB().Exec("insert into people (name, email) values('bob', 'bob@example.com')"); err != nil {
+		t.Fatal(err)
+	}
+	if n := migrationCount(t, db); n != 2 {
+		t.Fatalf("expected 2 migrations recorded, got %d", n)
+	}
+
+	// re-running would fail on the create and alter if reapplied
+	if err := db.Migrate(testMigrations); err != nil {
+		t.Fatal(err)
+	}
+	if n := migrationCount(t, db); n != 2 {
+		t.Fatalf("expected 2 migrations recorded after re-run, got %d", n)
+	}
+}
+
+func TestMigrateRollback(t *testing.T) {
+	db := migrateDBU(t)
+	defer db.Close()
+	migrations := append(testMigrations, Migration{
+		Version: 3,
+		Up:      "create table pets (id integer primary key); insert into nowhere values(1)",
+	})
+	if err := db.Migrate(migrations); err == nil {
+		t.Fatal("expected failing migration to return an error")
+	}
+	if n := migrationCount(t, db); n != 2 {
+		t.Fatalf("expected 2 migrations recorded, got %d", n)
+	}
+	var count int
+	if err := db.DB().QueryRow("select count(*) from sqlite_master where name='pets'").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Error("expected failed migration to be rolled back")
+	}
+}
+
+func TestMigrateDuplicate(t *testing.T) {
+	db := migrateDBU(t)
+	defer db.Close()
+	migrations := append(testMigrations, Migration{Version: 1, Up: "select 1"})
+	if err := db.Migrate(migrations); errors.Cause(err) != ErrDuplicateVersion {
+		t.Fatalf("expected ErrDuplicateVersion, got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package dbobj
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrMissingParam is returned when a named parameter has no value
+var ErrMissingParam = errors.New("missing named parameter")
+
+// BindNamed rewrites the :name parameters of the query as ? placeholders,
+// returning the values of the params in placeholder order. Repeated names are
+// bound once per use, and :: casts and quoted text are left as is
+func BindNamed(query string, params map[string]interface{}) (string, []interface{}, error) {
+	return bindNamed(SQLite, query, params)
+}
+
+// bindNamed rewrites the named parameters using the placeholders of the dialect
+func bindNamed(d Dialect, query string, params map[string]interface{}) (string, []interface{}, error) {
+	var b strings.Builder
+	var args []interface{}
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := quotedEnd(query, i)
+			b.WriteString(query[i : end+1])
+			i = end
+		case c == ':' && i+1 < len(query) && query[i+1] == ':':
+			b.WriteString("::")
+			i++
+		case c == ':' && i+1 < len(query) && isIdentStart(query[i+1]):
+			end := i + 1
+			for end < len(query) && isIdentPart(query[end]) {
+				end++
+			}
+			name := query[i+1 : end]
+			value, ok := params[name]
+			if !ok {
+				return "", nil, errors.Wrapf(ErrMissingParam, "param: %q", name)
+			}
+			args = append(args, value)
+			b.WriteString(d.Placeholder(len(args)))
+			i = end - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), args, nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+// ExecNamed executes the query with its :name parameters bound from params
+func (du *DBU) ExecNamed(query string, params map[string]interface{}) (rowsAffected, lastInsertID int64, err error) {
+	query, args, err := bindNamed(du.dialect, query, params)
+	if err != nil {
+		return 0, 0, err
+	}
+	du.debugf("Q: %s A: %v\n", query, args)
+	return du.Exec(query, args...)
+}
+
+// QueryNamed runs the query with its :name parameters bound from params
+func (du *DBU) QueryNamed(fn SetHandler, query string, params map[string]interface{}) error {
+	query, args, err := bindNamed(du.dialect, query, params)
+	if err != nil {
+		return err
+	}
+	du.debugf("Q: %s A: %v\n", query, args)
+	return du.Query(fn, query, args...)
+}
@@ -0,0 +1,46 @@
+package dbobj
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestBindNamed(t *testing.T) {
+	query, args, err := BindNamed("select id from t where a=:a and b=:b_2 or a=:a and c='x:y' and d=e::text", map[string]interface{}{"a": 1, "b_2": "two"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "select id from t where a=? and b=? or a=? and c='x:y' and d=e::text"
+	if query != want {
+		t.Errorf("expected %q, got %q", want, query)
+	}
+	if len(args) != 3 || args[0] != 1 || args[1] != "two" || args[2] != 1 {
+		t.Errorf("unexpected args: %v", args)
+	}
+	if query, _, _ := bindNamed(Postgres, "a=:a and b=:a", map[string]interface{}{"a": 1}); query != "a=$1 and b=$2" {
+		t.Errorf("unexpected postgres query: %q", query)
+	}
+	if _, _, err := BindNamed("a=:missing", nil); errors.Cause(err) != ErrMissingParam {
+		t.Errorf("expected ErrMissingParam, got %v", err)
+	}
+}
+
+func TestNamed(t *testing.T) {
+	db := structDBU(t)
+	params := map[string]interface{}{"name": "named", "kind": 77, "data": "x"}
+	_, id, err := db.ExecNamed("insert into structs (name, kind, data) values (:name, :kind, :data)", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s testStruct
+	fn := func() []interface{} {
+		return s.MemberPointers()
+	}
+	if err := db.QueryNamed(fn, "select "+s.SelectFields()+" from structs where id=:id and kind=:kind", map[string]interface{}{"id": id, "kind": 77}); err != nil {
+		t.Fatal(err)
+	}
+	if s.ID != id || s.Name != "named" || s.Data != "x" {
+		t.Errorf("unexpected object: %+v", s)
+	}
+}
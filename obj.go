@@ -2,14 +2,18 @@
 package dbobj
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"log"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/paulstuart/sqlite"
 	"github.com/pkg/errors"
 )
 
@@ -22,13 +26,109 @@ var (
 
 	// ErrNilWritePointers is returned when a list handler returns a nil slice
 	ErrNilWritePointers = errors.New("nil record dest members")
+
+	// ErrNotFound is returned when no record matches the query
+	ErrNotFound = errors.New("record not found")
+
+	// ErrNilList is returned when a nil DBList is provided
+	ErrNilList = errors.New("nil list")
+
+	// ErrUnknownColumn is returned when a column is not declared by the object
+	ErrUnknownColumn = errors.New("unknown column")
+
+	// ErrInvalidOrder is returned when ordering by a column the object doesn't declare
+	ErrInvalidOrder = errors.New("invalid order by column")
+
+	// ErrNoWhere is returned when a where clause is required but empty
+	ErrNoWhere = errors.New("empty where clause")
+
+	// ErrClosed is returned when the database has been closed
+	ErrClosed = errors.New("database is closed")
+
+	// ErrNoObjects is returned when no objects are provided
+	ErrNoObjects = errors.New("no objects")
+
+	// ErrInvalid is returned when an object fails validation
+	ErrInvalid = errors.New("validation failed")
+
+	// ErrNoTx is returned when the backend doesn't support transactions
+	ErrNoTx = errors.New("backend does not support transactions")
+
+	// ErrReadOnly is returned when writing with a read-only DBU
+	ErrReadOnly = errors.New("database is read-only")
+
+	// ErrUnknownUnique is returned when an object has no such unique constraint
+	ErrUnknownUnique = errors.New("unknown unique constraint")
+
+	// ErrColumnMismatch is returned when the number of receivers differs from the columns returned
+	ErrColumnMismatch = errors.New("column count mismatch")
 )
 
+// Validator is implemented by objects that check their values before being written
+type Validator interface {
+	Validate() error
+}
+
+// validate returns the result of the object's Validate method, if it has one
+func validate(o DBObject) error {
+	if v, ok := o.(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// Invalid returns ErrInvalid describing the column that failed validation
+func Invalid(table, column, reason string) error {
+	return errors.Wrapf(ErrInvalid, "table: %s column: %s %s", table, column, reason)
+}
+
+// UnknownColumn returns ErrUnknownColumn describing the column not declared by the table
+func UnknownColumn(table, column string) error {
+	return errors.Wrapf(ErrUnknownColumn, "table: %s column: %q", table, column)
+}
+
+// QueryError is the error returned when a query fails,
+// providing the context of the failing operation
+type QueryError struct {
+	Err   error
+	Query string
+	Table string
+	Args  []interface{}
+}
+
+// Error satisfies the error interface
+func (e QueryError) Error() string {
+	return fmt.Sprintf("table: %s query: %s args: %v: %v", e.Table, e.Query, e.Args, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e QueryError) Unwrap() error {
+	return e.Err
+}
+
+// Cause returns the underlying error, for use with errors.Cause
+func (e QueryError) Cause() error {
+	return e.Err
+}
+
+// queryError wraps a non-nil err as a QueryError
+func queryError(err error, o DBObject, query string, args ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	return QueryError{Err: err, Query: query, Table: tableName(o), Args: args}
+}
+
+// RowScanner scans the current row, as do *sql.Rows, *sql.Row and Common
+type RowScanner interface {
+	Scan(...interface{}) error
+}
+
 // Common Rows object between rqlite and /pkg/database/sql
 type Common interface {
+	RowScanner
 	Columns() []string
 	Next() bool
-	Scan(...interface{}) error
 }
 
 // SQLDB is a common interface for opening an sql db
@@ -55,39 +155,132 @@ type DBS interface {
 }
 
 // Query satisfies DBS interface
-func (du *DBU) Query(fn SetHandler, query string, args ...interface{}) error {
-	rows, err := du.db.Query(query, args...)
+func (du *DBU) Query(fn SetHandler, query string, args ...interface{}) (err error) {
+	if du.metrics != nil {
+		defer du.measure("query", query, time.Now(), &err)
+	}
+	return du.backend().Query(fn, query, args...)
+}
+
+// backend returns the DBS the DBU reads from
+func (du *DBU) backend() DBS {
+	if du.dbs != nil {
+		return du.dbs
+	}
+	return sqlWrapper{du.reader(), du.queryTimeout}
+}
+
+// writer returns the DBS the DBU writes to
+func (du *DBU) writer() DBS {
+	if du.dbs != nil {
+		return du.dbs
+	}
+	return sqlWrapper{du.db, du.queryTimeout}
+}
+
+// reader returns the read replica if set, otherwise the primary
+func (du *DBU) reader() *sql.DB {
+	if du.readDB != nil {
+		return du.readDB
+	}
+	return du.db
+}
+
+// SetReadDB sets a secondary (e.g., replica) handle used for queries,
+// while writes remain on the primary. A nil db reverts to the primary.
+func (du *DBU) SetReadDB(db *sql.DB) {
+	du.readDB = db
+}
+
+// sqlWrapper provides the DBS interface for a *sql.DB
+type sqlWrapper struct {
+	db      *sql.DB
+	timeout time.Duration // cancels statements running longer, if set
+}
+
+// context returns the context of a statement, which is cancelled after the timeout, if set
+func (s sqlWrapper) context() (context.Context, context.CancelFunc) {
+	return s.within(context.Background())
+}
+
+// within returns a context derived from ctx, which is also cancelled after the timeout, if set
+func (s sqlWrapper) within(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.timeout > 0 {
+		return context.WithTimeout(ctx, s.timeout)
+	}
+	return ctx, func() {}
+}
+
+// Query satisfies DBS interface
+func (s sqlWrapper) Query(fn SetHandler, query string, args ...interface{}) error {
+	if s.db == nil {
+		return ErrClosed
+	}
+	ctx, cancel := s.context()
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
 	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
 	for rows.Next() {
 		dest := fn()
 		if dest == nil {
 			return ErrNilWritePointers
 		}
+		if err = columnCheck(query, len(cols), len(dest)); err != nil {
+			return err
+		}
 		if err = rows.Scan(dest...); err != nil {
 			return err
 		}
 	}
+	return rows.Err()
+}
+
+// Exec satisfies DBS interface
+func (s sqlWrapper) Exec(query string, args ...interface{}) (rowsAffected, lastInsertID int64, err error) {
+	if s.db == nil {
+		return 0, 0, ErrClosed
+	}
+	ctx, cancel := s.context()
+	defer cancel()
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil || result == nil {
+		return 0, 0, err
+	}
+	rowsAffected, _ = result.RowsAffected()
+	lastInsertID, _ = result.LastInsertId()
+	return rowsAffected, lastInsertID, nil
+}
+
+// columnCheck returns ErrColumnMismatch if the receivers don't match the columns selected
+func columnCheck(query string, columns, receivers int) error {
+	if columns != receivers {
+		return errors.Wrapf(ErrColumnMismatch, "query: %s columns: %d receivers: %d", query, columns, receivers)
+	}
 	return nil
 }
 
 // MakeList is an alternative list creation interface
 func (du *DBU) MakeList(h ListHandler, query string, args ...interface{}) error {
-	rows, err := du.db.Query(query, args...)
-	if err != nil {
+	// the previous row is ready once the next is requested
+	pending := false
+	fn := func() []interface{} {
+		if pending {
+			h.Ready()
+		}
+		pending = true
+		return h.Receivers()
+	}
+	if err := du.Query(fn, query, args...); err != nil {
 		return err
 	}
-	defer rows.Close()
-	for rows.Next() {
-		dest := h.Receivers()
-		if dest == nil {
-			return ErrNilWritePointers
-		}
-		if err = rows.Scan(dest...); err != nil {
-			return err
-		}
+	if pending {
 		h.Ready()
 	}
 	return nil
@@ -95,36 +288,123 @@ func (du *DBU) MakeList(h ListHandler, query string, args ...interface{}) error
 
 // DBU is a DataBaseUnit
 type DBU struct {
-	db  *sql.DB
-	mu  sync.RWMutex
-	log *log.Logger
+	db     *sql.DB
+	readDB *sql.DB // optional replica for queries
+	dbs    DBS     // alternate backend, e.g., rqlite
+	mu     sync.RWMutex
+	log    Logger
+	dryRun bool
+
+	returnOnce sync.Once
+	canReturn  bool
+
+	cacheTTL time.Duration
+	cache    map[string]cacheEntry
+
+	resetSequence bool
+
+	dialect  Dialect
+	keyLocks *keyLocks
+
+	metrics MetricsFunc
+
+	skipUnknown bool // ImportCSV skips unknown header columns
+	readOnly    bool // writes return ErrReadOnly
+	closed      bool // set by Close, under mu
+
+	queryTimeout time.Duration // cancels queries and execs running longer, if set
 }
 
+// Exec satisfies DBS interface
 func (du *DBU) Exec(query string, args ...interface{}) (rowsAffected, lastInsertID int64, err error) {
-	var result sql.Result
-	// All locking should just happen here to avoid races
-	du.mu.Lock()
-	result, err = du.db.Exec(query, args...)
-	du.mu.Unlock()
-	if err != nil || result == nil {
+	if du.readOnly {
+		return 0, 0, ErrReadOnly
+	}
+	if du.dryRun {
+		du.debugf("DRY RUN Q: %s A: %v\n", query, args)
 		return
 	}
-	rowsAffected, _ = result.RowsAffected()
-	lastInsertID, _ = result.LastInsertId()
-	return
+	if du.metrics != nil {
+		defer du.measure("exec", query, time.Now(), &err)
+	}
+	// All locking should just happen here to avoid races
+	defer du.lockWrites()()
+	return du.writer().Exec(query, args...)
+}
+
+// Logger is the interface used for debug logging of queries
+type Logger interface {
+	Debugf(msg string, args ...interface{})
+}
+
+// StdLogger adapts a *log.Logger to the Logger interface
+type StdLogger struct {
+	*log.Logger
+}
+
+// Debugf satisfies the Logger interface
+func (l StdLogger) Debugf(msg string, args ...interface{}) {
+	l.Printf(msg, args...)
 }
 
 // SetLogger sets the logger for the db
-func (du *DBU) SetLogger(logger *log.Logger) {
+func (du *DBU) SetLogger(logger Logger) {
 	du.log = logger
 }
 
+// SetDryRun enables or disables dry-run mode, which logs writes without executing them
+func (du *DBU) SetDryRun(dryRun bool) {
+	du.dryRun = dryRun
+}
+
+// MetricsFunc receives the operation, query, elapsed time and error of each statement
+type MetricsFunc func(op string, query string, dur time.Duration, err error)
+
+// SetQueryTimeout sets the time after which a Query or Exec is cancelled,
+// for callers that don't use contexts. Zero disables the timeout.
+// The transactions of InsertMany, SaveMany, ImportCSV, ExecScript and each
+// migration are cancelled after it as a whole.
+// It applies to the database handles, not to other DBS backends
+func (du *DBU) SetQueryTimeout(d time.Duration) {
+	du.queryTimeout = d
+}
+
+// SetReadOnly sets whether writes are refused with ErrReadOnly, e.g., for reporting replicas.
+// It guards the DBU's own writes, complementing a read-only database connection
+func (du *DBU) SetReadOnly(readOnly bool) {
+	du.readOnly = readOnly
+}
+
+// SetMetrics sets the function called after every Exec and Query, e.g., to record latencies
+func (du *DBU) SetMetrics(fn MetricsFunc) {
+	du.metrics = fn
+}
+
+// measure reports the statement to the metrics function
+func (du *DBU) measure(op, query string, start time.Time, err *error) {
+	du.metrics(op, query, time.Since(start), *err)
+}
+
 func (du *DBU) debugf(msg string, args ...interface{}) {
 	if du.log != nil {
-		du.log.Printf(msg, args...)
+		du.log.Debugf(msg, args...)
 	}
 }
 
+// TableMeta describes the table of a generated object, e.g., for admin tooling
+type TableMeta struct {
+	Table  string      // sql table
+	Key    string      // sql key field
+	Fields []FieldMeta // the persisted fields, in select order
+}
+
+// FieldMeta describes a persisted field of a generated object
+type FieldMeta struct {
+	GoName  string // struct member name
+	SQLName string // sql column
+	GoType  string // Go type, as declared
+}
+
 // DBObject provides methods for object storage
 // The functions are generated for each object
 // annotated accordingly
@@ -171,209 +451,1307 @@ type DBObject interface {
 
 	// ModifiedBy returns the user id and timestamp of when the object was last modified
 	ModifiedBy(int64, time.Time)
+
+	// FieldMap returns a map of sql column names to member pointers
+	FieldMap() map[string]interface{}
 }
 
-func insertFields(o DBObject) string {
-	list := strings.Split(o.InsertFields(), ",")
-	keep := make([]string, 0, len(list))
-	for _, p := range list {
-		if p != o.KeyField() {
-			keep = append(keep, p)
-		}
-	}
-	return strings.Join(keep, ",")
+// AutoIncrementer is optionally implemented by objects to report
+// whether their key is assigned by the database on insert
+type AutoIncrementer interface {
+	AutoIncrement() bool
 }
 
-func setParams(params string) string {
-	list := strings.Split(params, ",")
-	for i, p := range list {
-		list[i] = fmt.Sprintf("%s=?", p)
+// autoIncrement reports whether the object key is assigned by the database
+func autoIncrement(o DBObject) bool {
+	if a, ok := o.(AutoIncrementer); ok {
+		return a.AutoIncrement()
 	}
-	return strings.Join(list, ",")
+	return true
 }
 
-func insertQuery(o DBObject) string {
-	p := Placeholders(len(o.InsertValues()))
-	return fmt.Sprintf("insert into %s (%s) values(%s)", o.TableName(), insertFields(o), p)
+// dynamicTabler is implemented by objects whose table is computed at runtime,
+// e.g., sharded tables such as events_2024_01
+type dynamicTabler interface {
+	DynamicTable() string
 }
 
-func replaceQuery(o DBObject) string {
-	p := Placeholders(len(o.InsertValues()))
-	return fmt.Sprintf("replace into %s (%s) values(%s)", o.TableName(), insertFields(o), p)
+// tableName returns the object's DynamicTable if present, otherwise its TableName
+func tableName(o DBObject) string {
+	if d, ok := o.(dynamicTabler); ok {
+		return d.DynamicTable()
+	}
+	return o.TableName()
 }
 
-func updateQuery(o DBObject) string {
-	return fmt.Sprintf("update %s set %s where %s=?", o.TableName(), setParams(insertFields(o)), o.KeyField())
+// columner is implemented by generated objects to list their sql columns
+type columner interface {
+	Columns() []string
 }
 
-func deleteQuery(o DBObject) string {
-	return fmt.Sprintf("delete from %s where %s=?", o.TableName(), o.KeyField())
+// insertColumns returns the columns of the object's insert values
+func insertColumns(o DBObject) []string {
+	if c, ok := o.(columner); ok {
+		return c.Columns()
+	}
+	return strings.Split(o.InsertFields(), ",")
 }
 
-// Add new object to datastore
-func (du *DBU) Add(o DBObject) error {
-	args := o.InsertValues()
-	query := insertQuery(o)
-	du.debugf("Q: %s A: %v\n", query, args)
-	_, last_id, err := du.Exec(query, args...)
-	if err == nil {
-		o.SetID(last_id)
+// selectColumns returns the columns of the object's member pointers
+func selectColumns(o DBObject) []string {
+	if c, ok := o.(columner); ok {
+		return c.Columns()
 	}
-	return err
+	fields := strings.Split(o.SelectFields(), ",")
+	for i, field := range fields {
+		fields[i] = strings.TrimSpace(field)
+	}
+	return fields
 }
 
-// Replace will replace an existing object in datastore
-func (du *DBU) Replace(o DBObject) error {
-	args := o.InsertValues()
-	_, last_id, err := du.Exec(replaceQuery(o), args)
-	if err != nil {
-		o.SetID(last_id)
+// insertFields returns the insert columns, excluding the key if the database assigns it
+func insertFields(o DBObject) []string {
+	cols := insertColumns(o)
+	if !autoIncrement(o) {
+		return cols
 	}
-	return err
+	keep := make([]string, 0, len(cols))
+	for _, col := range cols {
+		if col != o.KeyField() {
+			keep = append(keep, col)
+		}
+	}
+	return keep
 }
 
-// Save modified object in datastore
-func (du *DBU) Save(o DBObject) error {
-	_, _, err := du.Exec(updateQuery(o), o.UpdateValues()...)
-	return err
+// updateFields returns the columns set by an update, which never include the key,
+// so they line up with UpdateValues
+func updateFields(o DBObject) []string {
+	cols := insertColumns(o)
+	keep := make([]string, 0, len(cols))
+	for _, col := range cols {
+		if col != o.KeyField() {
+			keep = append(keep, col)
+		}
+	}
+	return keep
 }
 
-// Delete object from datastore
-func (du *DBU) Delete(o DBObject) error {
-	du.debugf("Q: %s  A: %v\n", deleteQuery(o), o.Key())
-	_, _, err := du.Exec(deleteQuery(o), o.Key())
-	return err
+// setParams returns the col=placeholder assignments of an update, numbered from start
+func setParams(d Dialect, cols []string, start int) string {
+	list := make([]string, len(cols))
+	for i, col := range cols {
+		list[i] = d.Quote(col) + "=" + d.Placeholder(start+i)
+	}
+	return strings.Join(list, ",")
 }
 
-// DeleteByID object from datastore by id
-func (du *DBU) DeleteByID(o DBObject, id interface{}) error {
-	du.debugf(deleteQuery(o), id)
-	_, _, err := du.Exec(deleteQuery(o), id)
-	return err
+// omitColumns leaves out the columns whose values are omitted by OmitEmpty,
+// e.g., zero values of fields tagged omitempty, so the column's default applies.
+// Other nil values are inserted as NULL
+func omitColumns(cols []string, args []interface{}) ([]string, []interface{}) {
+	if len(cols) != len(args) {
+		return cols, args
+	}
+	keepCols := cols[:0:0]
+	keepArgs := args[:0:0]
+	for i, arg := range args {
+		if _, ok := arg.(omitted); !ok {
+			keepCols = append(keepCols, cols[i])
+			keepArgs = append(keepArgs, arg)
+		}
+	}
+	return keepCols, keepArgs
 }
 
-// List objects from datastore
-func (du *DBU) List(list DBList) error {
-	return du.ListQuery(list, "")
+// insertQuery returns the insert statement and its args for the object,
+// with the values of sensitive columns redacted from logging
+func insertQuery(o DBObject, d Dialect) (string, []interface{}) {
+	cols, args := omitColumns(insertFields(o), o.InsertValues())
+	p := d.Placeholders(1, len(args))
+	return fmt.Sprintf("insert into %s (%s) values(%s)", d.Quote(tableName(o)), strings.Join(d.quoteAll(cols), ","), p), redact(o, cols, args)
 }
 
-// Find loads an object matching the given keys
-func (du *DBU) Find(o DBObject, keys map[string]interface{}) error {
-	where := make([]string, 0, len(keys))
-	what := make([]interface{}, 0, len(keys))
-	for k, v := range keys {
-		where = append(where, k+"=?")
-		what = append(what, v)
+// ignoreQuery returns an insert that skips rows conflicting with existing keys
+func ignoreQuery(o DBObject, d Dialect) (string, []interface{}) {
+	query, args := insertQuery(o, d)
+	switch d {
+	case Postgres:
+		return query + " on conflict do nothing", args
+	case MySQL:
+		return strings.Replace(query, "insert into", "insert ignore into", 1), args
 	}
-	query := fmt.Sprintf("select %s from %s where %s", o.SelectFields(), o.TableName(), strings.Join(where, " and "))
-	return du.get(o.MemberPointers(), query, what...)
+	return strings.Replace(query, "insert into", "insert or ignore into", 1), args
 }
 
-// FindBy loads an  object matching the given key/value
-func (du *DBU) FindBy(o DBObject, key string, value interface{}) error {
-	query := fmt.Sprintf("select %s from %s where %s=?", o.SelectFields(), o.TableName(), key)
-	return du.get(o.MemberPointers(), query, value)
+// replaceQuery returns the replace statement and its args for the object.
+// The key is included if set, so the existing row is replaced rather than
+// a new one being inserted with an assigned key.
+// The values of sensitive columns are redacted from logging
+func replaceQuery(o DBObject, d Dialect) (string, []interface{}) {
+	cols, args := omitColumns(insertFields(o), o.InsertValues())
+	if autoIncrement(o) && o.Key() != 0 {
+		cols = append([]string{o.KeyField()}, cols...)
+		args = append([]interface{}{o.Key()}, args...)
+	}
+	p := d.Placeholders(1, len(args))
+	return fmt.Sprintf("replace into %s (%s) values(%s)", d.Quote(tableName(o)), strings.Join(d.quoteAll(cols), ","), p), redact(o, cols, args)
 }
 
-// FindByID loads an object based on a given ID
-func (du *DBU) FindByID(o DBObject, value interface{}) error {
-	return du.FindBy(o, o.KeyField(), value)
+// updateArgs returns the args of the object's update, in the order of
+// updateFields followed by the key, with sensitive values redacted from logging
+func updateArgs(o DBObject) []interface{} {
+	return redact(o, append(updateFields(o), o.KeyField()), o.UpdateValues())
 }
 
-// FindSelf loads an object based on it's current ID
-func (du *DBU) FindSelf(o DBObject) error {
-	if len(o.KeyField()) == 0 {
-		return ErrNoKeyField
-	}
-	if o.Key() == 0 {
-		return ErrKeyMissing
-	}
-	return du.FindBy(o, o.KeyField(), o.Key())
+func updateQuery(o DBObject, d Dialect) string {
+	cols := updateFields(o)
+	return fmt.Sprintf("update %s set %s where %s=%s", d.Quote(tableName(o)), setParams(d, cols, 1), d.Quote(o.KeyField()), d.Placeholder(len(cols)+1))
 }
 
-// DBList is the interface for a list of db objects
-type DBList interface {
-	QueryString(extra string) string
-	Receivers() []interface{}
+func deleteQuery(o DBObject, d Dialect) string {
+	return fmt.Sprintf("delete from %s where %s=%s", d.Quote(tableName(o)), d.Quote(o.KeyField()), d.Placeholder(1))
 }
 
-// ListQuery updates a list of objects
-// TODO: handle args/vs no args for rqlite
-func (du *DBU) ListQuery(list DBList, extra string) error {
-	fn := func() []interface{} {
-		return list.Receivers()
-	}
-	query := list.QueryString(extra)
-	return du.Query(fn, query)
+// keyValuer is implemented by generated objects to list all of their key columns,
+// e.g., for composite or non-integer keys
+type keyValuer interface {
+	KeyFields() []string
+	KeyValues() []interface{}
 }
 
-// NewDBU returns a new DBU
-func NewDBU(file string, init bool, opener SQLDB) (*DBU, error) {
-	db, err := opener(file)
-	//return &DBU{dbs: sqlWrapper{db}}, err
-	return &DBU{db: db}, err
+// deleteKeyQuery returns the delete statement matching all of the object's key columns, and their values
+func deleteKeyQuery(o DBObject, d Dialect) (string, []interface{}) {
+	k, ok := o.(keyValuer)
+	if !ok {
+		return deleteQuery(o, d), []interface{}{o.Key()}
+	}
+	fields := k.KeyFields()
+	where := make([]string, len(fields))
+	for i, field := range fields {
+		where[i] = d.Quote(field) + "=" + d.Placeholder(i+1)
+	}
+	return fmt.Sprintf("delete from %s where %s", d.Quote(tableName(o)), strings.Join(where, " and ")), k.KeyValues()
 }
 
-// Placeholders returns SQLite values placeholders
-func Placeholders(n int) string {
-	var b strings.Builder
-	for i := 0; i < n; i++ {
-		if i > 0 {
-			b.WriteString(",")
+// keyValue returns the value of the object's key field, e.g., the text
+// of a string key, for which Key returns 0
+func keyValue(o DBObject) interface{} {
+	if ptr, ok := o.FieldMap()[o.KeyField()]; ok {
+		if v, err := memberValue(ptr); err == nil && v != nil {
+			return v
 		}
-		b.WriteString("?")
 	}
-	return b.String()
+	return o.Key()
 }
 
-// get is the low level db wrapper
-func (du *DBU) get(members []interface{}, query string, args ...interface{}) error {
-	du.debugf("Q: %s A:%v\n", query, args)
-	fn := func() []interface{} {
-		return members
+// Add new object to datastore
+func (du *DBU) Add(o DBObject) error {
+	if err := validate(o); err != nil {
+		return err
 	}
-	err := du.Query(fn, query, args...)
+	query, args := insertQuery(o, du.dialect)
+	du.debugf("Q: %s A: %v\n", query, args)
+	_, last_id, err := du.Exec(query, args...)
 	if err != nil {
-		log.Println("error on query: " + query + " -- " + err.Error())
-		return nil
+		return queryError(err, o, query, args...)
+	}
+	// some drivers and backends can't report the id, so don't clobber a preset key
+	if autoIncrement(o) && last_id != 0 {
+		o.SetID(last_id)
 	}
 	return nil
 }
 
-// DB returns the *sql.DB
-func (du *DBU) DB() *sql.DB {
-	return du.db
+// AddIgnore adds a new object to the datastore unless it conflicts with an existing key,
+// reporting whether the object was inserted
+func (du *DBU) AddIgnore(o DBObject) (bool, error) {
+	if err := validate(o); err != nil {
+		return false, err
+	}
+	query, args := ignoreQuery(o, du.dialect)
+	du.debugf("Q: %s A: %v\n", query, args)
+	rows, last_id, err := du.Exec(query, args...)
+	if err != nil {
+		return false, queryError(err, o, query, args...)
+	}
+	if rows == 0 {
+		return false, nil
+	}
+	if autoIncrement(o) && last_id != 0 {
+		o.SetID(last_id)
+	}
+	return true, nil
 }
 
-// InsertMany inserts multiple records as a single transaction
-func (du *DBU) InsertMany(query string, args ...[]interface{}) error {
-	tx, err := du.db.Begin()
-	if err != nil {
+// uniqueUpserter is implemented by generated objects with unique constraints
+type uniqueUpserter interface {
+	UpsertByUnique(d Dialect, table, name string) (string, []interface{})
+	UniqueFields(name string) []string
+}
+
+// UpsertBy adds the object, or updates the record conflicting with it on the named
+// unique constraint, e.g., a natural key alongside an autoincremented id.
+// The object's key is then loaded from the record
+func (du *DBU) UpsertBy(o DBObject, constraint string) error {
+	if err := validate(o); err != nil {
 		return err
 	}
-	stmt, err := tx.Prepare(query)
-	if err != nil {
-		if e := tx.Rollback(); e != nil {
-			log.Printf("prepare rollback error: %v\n", e)
-		}
-		return err
+	u, ok := o.(uniqueUpserter)
+	if !ok {
+		return errors.Wrapf(ErrUnknownUnique, "table: %s constraint: %q", tableName(o), constraint)
 	}
-	defer stmt.Close()
-	for _, arg := range args {
-		if _, err = stmt.Exec(arg...); err != nil {
-			if e := tx.Rollback(); e != nil {
-				log.Printf("exec rollback error: %v\n", e)
-			}
+	query, args := u.UpsertByUnique(du.dialect, tableName(o), constraint)
+	if query == "" {
+		return errors.Wrapf(ErrUnknownUnique, "table: %s constraint: %q", tableName(o), constraint)
+	}
+	// the generated upsert inserts every column but an autoincremented key
+	args = redact(o, insertFields(o), args)
+	du.debugf("Q: %s A: %v\n", query, args)
+	_, _, err := du.Exec(query, args...)
+	if err != nil || du.dryRun || !autoIncrement(o) {
+		du.InvalidateCache(o)
+		return queryError(err, o, query, args...)
+	}
+	// an update doesn't set the last insert id, so the key is looked up
+	fields := o.FieldMap()
+	cols := u.UniqueFields(constraint)
+	where := make([]string, len(cols))
+	values := make([]interface{}, len(cols))
+	for i, col := range cols {
+		where[i] = du.dialect.Quote(col) + "=" + du.dialect.Placeholder(i+1)
+		values[i] = reflect.ValueOf(fields[col]).Elem().Interface()
+	}
+	var id int64
+	query = fmt.Sprintf("select %s from %s where %s", du.dialect.Quote(o.KeyField()), du.dialect.Quote(tableName(o)), strings.Join(where, " and "))
+	if err := du.load([]interface{}{&id}, query, values...); err != nil {
+		return queryError(err, o, query, values...)
+	}
+	o.SetID(id)
+	// the record may have been updated, so its cache entry is stale
+	du.InvalidateCache(o)
+	return nil
+}
+
+// AddReturning adds a new object to the datastore and loads it back,
+// populating any columns set by database defaults.
+// If the database doesn't support insert ... returning the object is added then found
+func (du *DBU) AddReturning(o DBObject) error {
+	if !du.returning() {
+		if err := du.Add(o); err != nil || du.dryRun {
 			return err
 		}
+		return du.FindSelf(o)
 	}
-	return tx.Commit()
+	if err := validate(o); err != nil {
+		return err
+	}
+	query, args := insertQuery(o, du.dialect)
+	query += " returning " + du.dialect.selectFields(o)
+	du.debugf("Q: %s A: %v\n", query, args)
+	err := du.execQuery(o.MemberPointers, query, args...)
+	return queryError(err, o, query, args...)
 }
 
-// Close shuts down the database
-func (du *DBU) Close() {
-	if du.db != nil {
-		sqlite.Close(du.db)
-		du.db = nil
+// returning reports whether the database supports insert ... returning,
+// which SQLite has since version 3.35, but MySQL lacks
+func (du *DBU) returning() bool {
+	switch du.dialect {
+	case Postgres:
+		return true
+	case MySQL:
+		return false
 	}
+	du.returnOnce.Do(func() {
+		var version string
+		fn := func() []interface{} { return []interface{}{&version} }
+		if err := du.Query(fn, "select sqlite_version()"); err == nil {
+			du.canReturn = versionAtLeast(version, 3, 35)
+		}
+	})
+	return du.canReturn
+}
+
+// versionAtLeast reports whether the dotted version string is at least major.minor
+func versionAtLeast(version string, major, minor int) bool {
+	parts := strings.Split(version, ".")
+	if len(parts) < 2 {
+		return false
+	}
+	maj, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	mnr, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+	return maj > major || (maj == major && mnr >= minor)
+}
+
+// execQuery runs a write that returns rows, e.g., insert ... returning,
+// with the same guards, metrics and locking as Exec
+func (du *DBU) execQuery(fn SetHandler, query string, args ...interface{}) (err error) {
+	if du.readOnly {
+		return ErrReadOnly
+	}
+	if du.dryRun {
+		du.debugf("DRY RUN Q: %s A: %v\n", query, args)
+		return nil
+	}
+	if du.metrics != nil {
+		defer du.measure("exec", query, time.Now(), &err)
+	}
+	defer du.lockWrites()()
+	return du.writer().Query(fn, query, args...)
+}
+
+// Replace will replace an existing object in datastore
+func (du *DBU) Replace(o DBObject) error {
+	query, args := replaceQuery(o, du.dialect)
+	du.debugf("Q: %s A: %v\n", query, args)
+	_, lastID, err := du.Exec(query, args...)
+	if err == nil && o.Key() == 0 && autoIncrement(o) {
+		o.SetID(lastID)
+	}
+	du.InvalidateCache(o)
+	return err
+}
+
+// Save modified object in datastore
+func (du *DBU) Save(o DBObject) error {
+	_, err := du.SaveN(o)
+	return err
+}
+
+// SaveN saves the modified object, returning the number of rows affected,
+// which is zero if no record has the object's key
+func (du *DBU) SaveN(o DBObject) (int64, error) {
+	if err := validate(o); err != nil {
+		return 0, err
+	}
+	query, args := updateQuery(o, du.dialect), updateArgs(o)
+	du.debugf("Q: %s A: %v\n", query, args)
+	defer du.lockKey(o)()
+	affected, _, err := du.Exec(query, args...)
+	du.InvalidateCache(o)
+	return affected, queryError(err, o, query, args...)
+}
+
+// SaveFields saves only the named columns of the object, rejecting those
+// of fields tagged update:"false" with ErrUnknownColumn
+func (du *DBU) SaveFields(o DBObject, cols ...string) error {
+	if len(cols) == 0 {
+		return nil
+	}
+	fields := o.FieldMap()
+	args := make([]interface{}, 0, len(cols)+1)
+	for _, col := range cols {
+		ptr, ok := fields[col]
+		if !ok {
+			return UnknownColumn(tableName(o), col)
+		}
+		if !updatable(o, col) {
+			return errors.Wrapf(ErrUnknownColumn, "table: %s column: %q is not updatable", tableName(o), col)
+		}
+		args = append(args, reflect.ValueOf(ptr).Elem().Interface())
+	}
+	args = redact(o, append(cols[:len(cols):len(cols)], o.KeyField()), append(args, keyValue(o)))
+	query := fmt.Sprintf("update %s set %s where %s=%s", du.dialect.Quote(tableName(o)), setParams(du.dialect, cols, 1), du.dialect.Quote(o.KeyField()), du.dialect.Placeholder(len(cols)+1))
+	du.debugf("Q: %s A: %v\n", query, args)
+	defer du.lockKey(o)()
+	_, _, err := du.Exec(query, args...)
+	du.InvalidateCache(o)
+	return err
+}
+
+// patcher is implemented by generated objects to build partial updates
+type patcher interface {
+	UpdateFieldQuery(d Dialect, table string, cols ...string) (string, []interface{}, error)
+}
+
+// Patch saves only the named columns of the object, e.g., for HTTP PATCH requests,
+// using its generated UpdateFieldQuery if present. As with SaveFields, columns of
+// fields tagged update:"false" are rejected with ErrUnknownColumn
+func (du *DBU) Patch(o DBObject, cols ...string) error {
+	p, ok := o.(patcher)
+	if !ok {
+		return du.SaveFields(o, cols...)
+	}
+	if len(cols) == 0 {
+		return nil
+	}
+	for _, col := range cols {
+		if col == o.KeyField() {
+			return errors.Wrapf(ErrUnknownColumn, "table: %s column: %q is the key", tableName(o), col)
+		}
+		if err := validColumn(o, col); err != nil {
+			return err
+		}
+		if !updatable(o, col) {
+			return errors.Wrapf(ErrUnknownColumn, "table: %s column: %q is not updatable", tableName(o), col)
+		}
+	}
+	query, args, err := p.UpdateFieldQuery(du.dialect, tableName(o), cols...)
+	if err != nil {
+		return err
+	}
+	args = redact(o, append(cols[:len(cols):len(cols)], o.KeyField()), args)
+	du.debugf("Q: %s A: %v\n", query, args)
+	defer du.lockKey(o)()
+	_, _, err = du.Exec(query, args...)
+	du.InvalidateCache(o)
+	return queryError(err, o, query, args...)
+}
+
+// SaveIfChanged saves current only if it differs from original
+func (du *DBU) SaveIfChanged(current, original DBObject) error {
+	if equal(current, original) {
+		return nil
+	}
+	return du.Save(current)
+}
+
+// equal reports whether the objects are equal, using their generated
+// Equal method if present, otherwise comparing their member values
+func equal(a, b DBObject) bool {
+	if m := reflect.ValueOf(a).MethodByName("Equal"); m.IsValid() {
+		mt := m.Type()
+		arg := reflect.ValueOf(b)
+		if mt.NumIn() == 1 && mt.NumOut() == 1 && mt.Out(0).Kind() == reflect.Bool && arg.Type().AssignableTo(mt.In(0)) {
+			return m.Call([]reflect.Value{arg})[0].Bool()
+		}
+	}
+	return sameValues(a.MemberPointers(), b.MemberPointers())
+}
+
+// sameValues reports whether the values pointed to are equal
+func sameValues(a, b []interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !sameValue(reflect.ValueOf(a[i]).Elem().Interface(), reflect.ValueOf(b[i]).Elem().Interface()) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameValue compares times by instant and everything else deeply
+func sameValue(a, b interface{}) bool {
+	if t, ok := asTime(a); ok {
+		if u, ok := asTime(b); ok {
+			return t.Equal(u)
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// asTime returns v as a time.Time if it is one, or a converter of one
+func asTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case NullableTime:
+		return time.Time(t), true
+	case UnixTime:
+		return time.Time(t), true
+	}
+	return time.Time{}, false
+}
+
+// creator is implemented by generated objects with creation audit fields
+type creator interface {
+	CreatedBy(int64, time.Time)
+}
+
+// AddAs sets the creation audit fields, if any, for the given user and adds the object
+func (du *DBU) AddAs(o DBObject, userID int64) error {
+	if c, ok := o.(creator); ok {
+		c.CreatedBy(userID, time.Now())
+	}
+	return du.Add(o)
+}
+
+// SaveAs updates the audit fields for the given user and saves the object
+func (du *DBU) SaveAs(o DBObject, userID int64) error {
+	o.ModifiedBy(userID, time.Now())
+	return du.Save(o)
+}
+
+// Delete object from datastore
+func (du *DBU) Delete(o DBObject) error {
+	_, err := du.DeleteN(o)
+	return err
+}
+
+// DeleteN deletes the object, returning the number of rows affected,
+// which is zero if no record has the object's key
+func (du *DBU) DeleteN(o DBObject) (int64, error) {
+	query, args := deleteKeyQuery(o, du.dialect)
+	du.debugf("Q: %s  A: %v\n", query, args)
+	defer du.lockKey(o)()
+	affected, _, err := du.Exec(query, args...)
+	du.InvalidateCache(o)
+	return affected, queryError(err, o, query, args...)
+}
+
+// DeleteByID object from datastore by id
+func (du *DBU) DeleteByID(o DBObject, id interface{}) error {
+	query := deleteQuery(o, du.dialect)
+	du.debugf("Q: %s A: %v\n", query, id)
+	_, _, err := du.Exec(query, id)
+	du.invalidate(o, id)
+	return err
+}
+
+// DeleteByKeys deletes the objects matching all of the given key columns,
+// e.g., for tables with a composite primary key, returning the number deleted
+func (du *DBU) DeleteByKeys(o DBObject, keys map[string]interface{}) (int64, error) {
+	if len(keys) == 0 {
+		return 0, ErrNoWhere
+	}
+	columns := make([]string, 0, len(keys))
+	for k := range keys {
+		if err := validColumn(o, k); err != nil {
+			return 0, err
+		}
+		columns = append(columns, k)
+	}
+	sort.Strings(columns)
+	where := make([]string, len(columns))
+	args := make([]interface{}, len(columns))
+	for i, k := range columns {
+		where[i] = du.dialect.Quote(k) + "=" + du.dialect.Placeholder(i+1)
+		args[i] = keys[k]
+	}
+	query := fmt.Sprintf("delete from %s where %s", du.dialect.Quote(tableName(o)), strings.Join(where, " and "))
+	return du.deleteRows(o, query, args...)
+}
+
+// DeleteWhere deletes all objects matching the where clause, returning the number deleted.
+// An empty where clause is rejected; use DeleteAll to empty a table
+func (du *DBU) DeleteWhere(o DBObject, where string, args ...interface{}) (int64, error) {
+	if strings.TrimSpace(where) == "" {
+		return 0, ErrNoWhere
+	}
+	query := fmt.Sprintf("delete from %s where %s", du.dialect.Quote(tableName(o)), where)
+	return du.deleteRows(o, query, args...)
+}
+
+// DeleteAll deletes every object in the table, returning the number deleted
+func (du *DBU) DeleteAll(o DBObject) (int64, error) {
+	return du.deleteRows(o, "delete from "+du.dialect.Quote(tableName(o)))
+}
+
+// Truncate deletes all of the object's records, resetting the table's
+// autoincrement sequence if the DBU was created with Options.ResetSequence
+func (du *DBU) Truncate(o DBObject) error {
+	if _, err := du.DeleteAll(o); err != nil {
+		return err
+	}
+	if !du.resetSequence {
+		return nil
+	}
+	// sqlite_sequence only exists once an AUTOINCREMENT table has been created
+	var count int
+	query := "select count(*) from sqlite_master where type='table' and name='sqlite_sequence'"
+	if err := du.ScanValue(&count, query); err != nil || count == 0 {
+		return err
+	}
+	query = "delete from sqlite_sequence where name=" + du.dialect.Placeholder(1)
+	du.debugf("Q: %s A: %v\n", query, tableName(o))
+	_, _, err := du.Exec(query, tableName(o))
+	return err
+}
+
+func (du *DBU) deleteRows(o DBObject, query string, args ...interface{}) (int64, error) {
+	du.debugf("Q: %s A: %v\n", query, args)
+	affected, _, err := du.Exec(query, args...)
+	du.invalidateTable(o)
+	return affected, err
+}
+
+// List objects from datastore
+func (du *DBU) List(list DBList) error {
+	return du.ListQuery(list, "")
+}
+
+// Find loads an object matching the given keys
+func (du *DBU) Find(o DBObject, keys map[string]interface{}) error {
+	where := make([]string, 0, len(keys))
+	what := make([]interface{}, 0, len(keys))
+	for k, v := range keys {
+		if err := validColumn(o, k); err != nil {
+			return err
+		}
+		what = append(what, v)
+		where = append(where, du.dialect.Quote(k)+"="+du.dialect.Placeholder(len(what)))
+	}
+	query := fmt.Sprintf("select %s from %s where %s", du.dialect.selectFields(o), du.dialect.Quote(tableName(o)), strings.Join(where, " and "))
+	return du.get(o, query, what...)
+}
+
+// Count returns the number of the object's records matching the optional where clause
+func (du *DBU) Count(o DBObject, where string, args ...interface{}) (int64, error) {
+	query := "select count(*) from " + du.dialect.Quote(tableName(o))
+	if where != "" {
+		query += " where " + where
+	}
+	var count int64
+	err := du.load([]interface{}{&count}, query, args...)
+	return count, err
+}
+
+// ScanValue scans the first row of a single column query into dest,
+// returning ErrNotFound if there are no rows
+func (du *DBU) ScanValue(dest interface{}, query string, args ...interface{}) error {
+	du.debugf("Q: %s A:%v\n", query, args)
+	found := false
+	var discard interface{}
+	fn := func() []interface{} {
+		if found {
+			return []interface{}{&discard}
+		}
+		found = true
+		return []interface{}{dest}
+	}
+	if err := du.Query(fn, query, args...); err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// QueryIntMap scans the rows of a two column query, e.g., of a group by count,
+// into a map of the first column to the second
+func (du *DBU) QueryIntMap(query string, args ...interface{}) (map[int64]int64, error) {
+	du.debugf("Q: %s A:%v\n", query, args)
+	m := make(map[int64]int64)
+	var key, count int64
+	scanned := false
+	fn := func() []interface{} {
+		// the previous row is ready once the next is requested
+		if scanned {
+			m[key] = count
+		}
+		scanned = true
+		return []interface{}{&key, &count}
+	}
+	if err := du.Query(fn, query, args...); err != nil {
+		return nil, err
+	}
+	if scanned {
+		m[key] = count
+	}
+	return m, nil
+}
+
+// QueryStringMap scans the rows of a two column query, e.g., of a group by count,
+// into a map of the first column to the second
+func (du *DBU) QueryStringMap(query string, args ...interface{}) (map[string]int64, error) {
+	du.debugf("Q: %s A:%v\n", query, args)
+	m := make(map[string]int64)
+	var key string
+	var count int64
+	scanned := false
+	fn := func() []interface{} {
+		// the previous row is ready once the next is requested
+		if scanned {
+			m[key] = count
+		}
+		scanned = true
+		return []interface{}{&key, &count}
+	}
+	if err := du.Query(fn, query, args...); err != nil {
+		return nil, err
+	}
+	if scanned {
+		m[key] = count
+	}
+	return m, nil
+}
+
+// Exists reports whether any of the object's records match the where clause
+func (du *DBU) Exists(o DBObject, where string, args ...interface{}) (bool, error) {
+	query := "select 1 from " + du.dialect.Quote(tableName(o))
+	if where != "" {
+		query += " where " + where
+	}
+	var one int
+	switch err := du.load([]interface{}{&one}, query+" limit 1", args...); err {
+	case nil:
+		return true, nil
+	case ErrNotFound:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// validColumn returns ErrUnknownColumn if column is not one of the object's select fields
+func validColumn(o DBObject, column string) error {
+	for _, field := range selectColumns(o) {
+		if field == column {
+			return nil
+		}
+	}
+	return errors.Wrapf(ErrUnknownColumn, "table: %s column: %q", tableName(o), column)
+}
+
+// FindBy loads an  object matching the given key/value
+func (du *DBU) FindBy(o DBObject, key string, value interface{}) error {
+	if err := validColumn(o, key); err != nil {
+		return err
+	}
+	query := fmt.Sprintf("select %s from %s where %s=%s", du.dialect.selectFields(o), du.dialect.Quote(tableName(o)), du.dialect.Quote(key), du.dialect.Placeholder(1))
+	return du.get(o, query, value)
+}
+
+// FindWhere loads an object matching the given where clause and args
+func (du *DBU) FindWhere(o DBObject, where string, args ...interface{}) error {
+	query := fmt.Sprintf("select %s from %s where %s", du.dialect.selectFields(o), du.dialect.Quote(tableName(o)), where)
+	return du.load(o.MemberPointers(), query, args...)
+}
+
+// qualifier is implemented by generated objects to list table qualified select fields
+type qualifier interface {
+	QualifiedSelectFields() string
+}
+
+// QualifiedFields returns the object's select fields prefixed by its table name,
+// with reserved words double quoted
+func QualifiedFields(o DBObject) string {
+	if q, ok := o.(qualifier); ok {
+		return q.QualifiedSelectFields()
+	}
+	return SQLite.qualifiedFields(o)
+}
+
+// qualifiedFields returns the object's select fields prefixed by its table name,
+// with reserved words quoted for the dialect
+func (d Dialect) qualifiedFields(o DBObject) string {
+	table := d.Quote(tableName(o))
+	fields := selectColumns(o)
+	for i, field := range fields {
+		fields[i] = table + "." + d.Quote(field)
+	}
+	return strings.Join(fields, ",")
+}
+
+// joinQuery returns a select of the qualified fields of all objects
+// from the first object's table, followed by the join and where clauses
+func joinQuery(d Dialect, objs []DBObject, join, where string) string {
+	fields := make([]string, len(objs))
+	for i, o := range objs {
+		fields[i] = d.qualifiedFields(o)
+	}
+	query := fmt.Sprintf("select %s from %s", strings.Join(fields, ","), d.Quote(tableName(objs[0])))
+	if join != "" {
+		query += " " + join
+	}
+	if where != "" {
+		query += " where " + where
+	}
+	return query
+}
+
+// FindJoined loads the objects from a single row of a join query,
+// e.g., FindJoined([]DBObject{&user, &group}, "join groups on groups.id=users.gid", "users.id=?", 1)
+func (du *DBU) FindJoined(objs []DBObject, join, where string, args ...interface{}) error {
+	if len(objs) == 0 {
+		return ErrNoObjects
+	}
+	var members []interface{}
+	for _, o := range objs {
+		members = append(members, o.MemberPointers()...)
+	}
+	return du.load(members, joinQuery(du.dialect, objs, join, where), args...)
+}
+
+// FindColumns loads only the named columns of the object matching the given key/value
+func (du *DBU) FindColumns(o DBObject, cols []string, key string, value interface{}) error {
+	if err := validColumn(o, key); err != nil {
+		return err
+	}
+	fields := o.FieldMap()
+	members := make([]interface{}, 0, len(cols))
+	for _, col := range cols {
+		ptr, ok := fields[col]
+		if !ok {
+			return UnknownColumn(tableName(o), col)
+		}
+		members = append(members, ptr)
+	}
+	query := fmt.Sprintf("select %s from %s where %s=%s", strings.Join(du.dialect.quoteAll(cols), ","), du.dialect.Quote(tableName(o)), du.dialect.Quote(key), du.dialect.Placeholder(1))
+	return du.load(members, query, value)
+}
+
+// FindByID loads an object based on a given ID,
+// returning ErrNotFound if there is no such record
+func (du *DBU) FindByID(o DBObject, value interface{}) error {
+	if du.cacheLoad(o, value) {
+		return nil
+	}
+	query := fmt.Sprintf("select %s from %s where %s=%s", du.dialect.selectFields(o), du.dialect.Quote(tableName(o)), du.dialect.Quote(o.KeyField()), du.dialect.Placeholder(1))
+	if err := du.load(o.MemberPointers(), query, value); err != nil {
+		return err
+	}
+	du.cacheStore(o, value)
+	return nil
+}
+
+// FindByIDs loads the objects of o's type with the given ids in a single query,
+// returned in the order of ids, with missing ids omitted
+func (du *DBU) FindByIDs(o DBObject, ids []interface{}) ([]DBObject, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	query := fmt.Sprintf("select %s from %s where %s in (%s)", du.dialect.selectFields(o), du.dialect.Quote(tableName(o)), du.dialect.Quote(o.KeyField()), du.dialect.Placeholders(1, len(ids)))
+	var rows []DBObject
+	fn := func() []interface{} {
+		obj := newObject(o)
+		rows = append(rows, obj)
+		return obj.MemberPointers()
+	}
+	if err := du.Query(fn, query, ids...); err != nil {
+		return nil, queryError(err, o, query, ids...)
+	}
+	found := make(map[string]DBObject, len(rows))
+	for _, obj := range rows {
+		found[fmt.Sprint(keyValue(obj))] = obj
+	}
+	list := make([]DBObject, 0, len(rows))
+	for _, id := range ids {
+		if obj, ok := found[fmt.Sprint(id)]; ok {
+			list = append(list, obj)
+		}
+	}
+	return list, nil
+}
+
+// newObject returns a new object of o's type, using its NewObj method if present
+func newObject(o DBObject) DBObject {
+	if n, ok := o.(newer); ok {
+		if obj, ok := n.NewObj().(DBObject); ok {
+			return obj
+		}
+	}
+	return reflect.New(reflect.TypeOf(o).Elem()).Interface().(DBObject)
+}
+
+// FindSelf loads an object based on it's current ID,
+// returning ErrNotFound if there is no such record
+func (du *DBU) FindSelf(o DBObject) error {
+	if len(o.KeyField()) == 0 {
+		return ErrNoKeyField
+	}
+	if o.Key() == 0 {
+		return ErrKeyMissing
+	}
+	return du.FindByID(o, o.Key())
+}
+
+// Refresh reloads the object in place from its current row, bypassing the cache,
+// e.g., after another process modified it. It returns ErrNotFound if the row is gone
+func (du *DBU) Refresh(o DBObject) error {
+	if len(o.KeyField()) == 0 {
+		return ErrNoKeyField
+	}
+	key := keyValue(o)
+	if key == nil || reflect.ValueOf(key).IsZero() {
+		return ErrKeyMissing
+	}
+	du.InvalidateCache(o)
+	return du.FindByID(o, key)
+}
+
+// DBList is the interface for a list of db objects
+type DBList interface {
+	QueryString(extra string) string
+	Receivers() []interface{}
+}
+
+// ListQuery updates a list of objects
+func (du *DBU) ListQuery(list DBList, extra string) error {
+	return du.ListQueryArgs(list, extra)
+}
+
+// ListQueryArgs updates a list of objects, binding args to the placeholders of extra.
+// It reads from the read replica, if set
+func (du *DBU) ListQueryArgs(list DBList, extra string, args ...interface{}) error {
+	if isNil(list) {
+		return ErrNilList
+	}
+	fn := func() []interface{} {
+		return list.Receivers()
+	}
+	query, args := expandArgs(du.dialect, list.QueryString(extra), args)
+	du.debugf("Q: %s A:%v\n", query, args)
+	return du.Query(fn, query, args...)
+}
+
+// ListAfter updates a list with up to limit objects whose keyCol is greater than afterKey,
+// in keyCol order, for keyset pagination. A nil afterKey starts from the first object,
+// and the next page starts after the last key of the list
+func (du *DBU) ListAfter(list DBList, keyCol string, afterKey interface{}, limit int) error {
+	if isNil(list) {
+		return ErrNilList
+	}
+	if !identifier.MatchString(keyCol) {
+		return errors.Wrapf(ErrInvalidColumn, "column: %q", keyCol)
+	}
+	if !listColumns(list)[strings.ToLower(keyCol)] {
+		return errors.Wrapf(ErrUnknownColumn, "column: %q", keyCol)
+	}
+	col := du.dialect.Quote(keyCol)
+	var args []interface{}
+	extra := col + " is not null"
+	if afterKey != nil {
+		extra = col + " > " + du.dialect.Placeholder(1)
+		args = append(args, afterKey)
+	}
+	extra += " order by " + col
+	if limit > 0 {
+		extra += " limit " + du.dialect.Placeholder(len(args)+1)
+		args = append(args, limit)
+	}
+	return du.ListQueryArgs(list, extra, args...)
+}
+
+// listColumns returns the columns selected by the list's query
+func listColumns(list DBList) map[string]bool {
+	query := strings.TrimSpace(list.QueryString(""))
+	fields := strings.TrimPrefix(strings.ToLower(query), "select ")
+	if i := strings.Index(fields, " from "); i >= 0 {
+		fields = fields[:i]
+	}
+	columns := make(map[string]bool)
+	for _, field := range strings.Split(fields, ",") {
+		field = strings.TrimSpace(field)
+		if i := strings.LastIndex(field, "."); i >= 0 {
+			field = field[i+1:]
+		}
+		columns[strings.Trim(field, "`\"")] = true
+	}
+	return columns
+}
+
+// isNil reports whether v is nil or an interface holding a nil pointer
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+// NewDBU returns a new DBU
+func NewDBU(file string, init bool, opener SQLDB) (*DBU, error) {
+	db, err := opener(file)
+	//return &DBU{dbs: sqlWrapper{db}}, err
+	return &DBU{db: db}, err
+}
+
+// Options are the SQLite PRAGMAs applied when opening a database.
+// As busy_timeout and foreign_keys apply per connection, they are also passed
+// as go-sqlite3 DSN parameters, so every connection of the pool has them
+type Options struct {
+	BusyTimeout time.Duration // busy_timeout, if non-zero
+	JournalMode string        // journal_mode, e.g., "WAL", if set
+	ForeignKeys bool          // enable foreign_keys enforcement
+
+	ResetSequence bool // Truncate also resets autoincrement sequences
+}
+
+// pragmas returns the PRAGMA statements for the options
+func (opts Options) pragmas() []string {
+	var list []string
+	if opts.BusyTimeout > 0 {
+		list = append(list, fmt.Sprintf("PRAGMA busy_timeout = %d", opts.BusyTimeout/time.Millisecond))
+	}
+	if len(opts.JournalMode) > 0 {
+		list = append(list, "PRAGMA journal_mode = "+opts.JournalMode)
+	}
+	if opts.ForeignKeys {
+		list = append(list, "PRAGMA foreign_keys = ON")
+	}
+	return list
+}
+
+// dsn returns the file with the go-sqlite3 parameters of the per connection options
+func (opts Options) dsn(file string) string {
+	var params []string
+	if opts.BusyTimeout > 0 {
+		params = append(params, fmt.Sprintf("_busy_timeout=%d", opts.BusyTimeout/time.Millisecond))
+	}
+	if opts.ForeignKeys {
+		params = append(params, "_foreign_keys=1")
+	}
+	if len(params) == 0 {
+		return file
+	}
+	sep := "?"
+	if strings.Contains(file, "?") {
+		sep = "&"
+	}
+	return file + sep + strings.Join(params, "&")
+}
+
+// NewDBUWithOptions returns a new DBU with the options applied
+func NewDBUWithOptions(file string, opts Options, opener SQLDB) (*DBU, error) {
+	db, err := opener(opts.dsn(file))
+	if err != nil {
+		return nil, err
+	}
+	for _, pragma := range opts.pragmas() {
+		if _, err := db.Exec(pragma); err != nil {
+			db.Close()
+			return nil, errors.Wrapf(err, "pragma failed: %s", pragma)
+		}
+	}
+	return &DBU{db: db, resetSequence: opts.ResetSequence}, nil
+}
+
+// FormatObject returns a readable representation of an object's column values,
+// e.g., structs{id=1 name=abc kind=23}, for generated String methods
+func FormatObject(table, columns string, values ...interface{}) string {
+	var b strings.Builder
+	b.WriteString(table)
+	b.WriteString("{")
+	for i, col := range strings.Split(columns, ",") {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		b.WriteString(col)
+		b.WriteString("=")
+		if i >= len(values) {
+			continue
+		}
+		if v, ok := values[i].([]byte); ok {
+			b.Write(v)
+		} else {
+			fmt.Fprint(&b, values[i])
+		}
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// Placeholders returns SQLite values placeholders
+func Placeholders(n int) string {
+	return SQLite.Placeholders(1, n)
+}
+
+// get is the low level db wrapper
+func (du *DBU) get(o DBObject, query string, args ...interface{}) error {
+	if err := du.load(o.MemberPointers(), query, args...); err != nil && err != ErrNotFound {
+		return queryError(err, o, query, args...)
+	}
+	return nil
+}
+
+// load scans the query results into members, returning ErrNotFound if there are no rows
+func (du *DBU) load(members []interface{}, query string, args ...interface{}) error {
+	query, args = expandArgs(du.dialect, query, args)
+	du.debugf("Q: %s A:%v\n", query, args)
+	found := false
+	fn := func() []interface{} {
+		found = true
+		return members
+	}
+	err := du.Query(fn, query, args...)
+	if err != nil {
+		log.Println("error on query: " + query + " -- " + err.Error())
+		return err
+	}
+	if !found {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// noTx returns the error for transactions without a *sql.DB
+func (du *DBU) noTx() error {
+	if du.dbs != nil {
+		return ErrNoTx
+	}
+	return ErrClosed
+}
+
+// DB returns the *sql.DB
+func (du *DBU) DB() *sql.DB {
+	return du.db
+}
+
+// InsertMany inserts multiple records as a single transaction
+func (du *DBU) InsertMany(query string, args ...[]interface{}) error {
+	return du.InsertManyContext(context.Background(), query, args...)
+}
+
+// InsertManyContext inserts multiple records as a single transaction,
+// rolling back and returning ctx.Err() if the context is done before completion
+func (du *DBU) InsertManyContext(ctx context.Context, query string, args ...[]interface{}) error {
+	if du.readOnly {
+		return ErrReadOnly
+	}
+	if du.dryRun {
+		for _, arg := range args {
+			du.debugf("DRY RUN Q: %s A: %v\n", query, arg)
+		}
+		return nil
+	}
+	if du.db == nil {
+		return du.noTx()
+	}
+	ctx, cancel := sqlWrapper{du.db, du.queryTimeout}.within(ctx)
+	defer cancel()
+	tx, err := du.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	rollback := func(msg string) {
+		if e := tx.Rollback(); e != nil && e != sql.ErrTxDone {
+			log.Printf("%s rollback error: %v\n", msg, e)
+		}
+	}
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		rollback("prepare")
+		return err
+	}
+	defer stmt.Close()
+	for _, arg := range args {
+		if err = ctx.Err(); err != nil {
+			rollback("cancel")
+			return err
+		}
+		if _, err = stmt.ExecContext(ctx, arg...); err != nil {
+			rollback("exec")
+			if e := ctx.Err(); e != nil {
+				return e
+			}
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SaveMany saves multiple objects of the same table as a single transaction,
+// validating them all before any is saved
+func (du *DBU) SaveMany(objs ...DBObject) (err error) {
+	if len(objs) == 0 {
+		return nil
+	}
+	table := tableName(objs[0])
+	for _, o := range objs[1:] {
+		if tableName(o) != table {
+			return fmt.Errorf("mixed tables in SaveMany: %s and %s", table, tableName(o))
+		}
+	}
+	for _, o := range objs {
+		if err := validate(o); err != nil {
+			return err
+		}
+	}
+	if du.readOnly {
+		return ErrReadOnly
+	}
+	query := updateQuery(objs[0], du.dialect)
+	if du.dryRun {
+		for _, o := range objs {
+			du.debugf("DRY RUN Q: %s A: %v\n", query, updateArgs(o))
+		}
+		return nil
+	}
+	if du.db == nil {
+		return du.noTx()
+	}
+	if du.metrics != nil {
+		defer du.measure("exec", query, time.Now(), &err)
+	}
+	defer du.lockKeys(objs)()
+	ctx, cancel := sqlWrapper{du.db, du.queryTimeout}.context()
+	defer cancel()
+	tx, err := du.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		if e := tx.Rollback(); e != nil && e != sql.ErrTxDone {
+			log.Printf("prepare rollback error: %v\n", e)
+		}
+		return err
+	}
+	defer stmt.Close()
+	for _, o := range objs {
+		if _, err = stmt.ExecContext(ctx, o.UpdateValues()...); err != nil {
+			if e := tx.Rollback(); e != nil && e != sql.ErrTxDone {
+				log.Printf("exec rollback error: %v\n", e)
+			}
+			return err
+		}
+	}
+	err = tx.Commit()
+	for _, o := range objs {
+		du.InvalidateCache(o)
+	}
+	return err
+}
+
+// pinger is implemented by backends that can verify their connection
+type pinger interface {
+	Ping(context.Context) error
+}
+
+// Ping verifies the database connection is alive
+func (du *DBU) Ping(ctx context.Context) error {
+	if p, ok := du.dbs.(pinger); ok {
+		return p.Ping(ctx)
+	}
+	if du.db == nil {
+		return ErrClosed
+	}
+	return du.db.PingContext(ctx)
+}
+
+// Stats returns the connection pool statistics
+func (du *DBU) Stats() sql.DBStats {
+	if du.db == nil {
+		return sql.DBStats{}
+	}
+	return du.db.Stats()
+}
+
+// Close shuts down the database, checkpointing the SQLite WAL first, or the
+// alternate backend if it's an io.Closer, and returns ErrClosed if it was already
+// closed. Statements are prepared per transaction and closed with it, so none are
+// left open. The lock keeps serialized writes from racing the close
+func (du *DBU) Close() error {
+	du.mu.Lock()
+	if du.closed {
+		du.mu.Unlock()
+		return ErrClosed
+	}
+	db, dbs := du.db, du.dbs
+	du.db, du.dbs, du.closed = nil, nil, true
+	du.mu.Unlock()
+	if db == nil {
+		if c, ok := dbs.(io.Closer); ok {
+			return c.Close()
+		}
+		return nil
+	}
+	var err error
+	if du.dialect == SQLite {
+		_, err = db.Exec("PRAGMA wal_checkpoint(TRUNCATE)")
+	}
+	if cerr := db.Close(); cerr != nil {
+		return cerr
+	}
+	return err
 }
@@ -1,12 +1,21 @@
 package dbobj
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/paulstuart/sqlite"
+	"github.com/pkg/errors"
 )
 
 type testStruct struct {
@@ -54,7 +63,7 @@ func (s *testStruct) UpdateValues() []interface{} {
 }
 
 func (s *testStruct) MemberPointers() []interface{} {
-	return []interface{}{&s.ID, &s.Name, &s.Kind, &s.Data, &s.Modified}
+	return []interface{}{&s.ID, &s.Name, &s.Kind, &s.Data, (*NullableTime)(&s.Modified)}
 }
 
 func (s *testStruct) InsertValues() []interface{} {
@@ -73,6 +82,27 @@ func (s *testStruct) ModifiedBy(u int64, t time.Time) {
 	s.Modified = t
 }
 
+func (s *testStruct) Equal(other *testStruct) bool {
+	if s == nil || other == nil {
+		return s == other
+	}
+	return s.ID == other.ID &&
+		s.Name == other.Name &&
+		s.Kind == other.Kind &&
+		s.Data == other.Data &&
+		s.Modified.Equal(other.Modified)
+}
+
+func (s *testStruct) FieldMap() map[string]interface{} {
+	return map[string]interface{}{
+		"id":       &s.ID,
+		"name":     &s.Name,
+		"kind":     &s.Kind,
+		"data":     &s.Data,
+		"modified": (*NullableTime)(&s.Modified),
+	}
+}
+
 /*
 type testStrings struct {
 	ID       int64     `sql:"id" key:"true" table:"structs"`
@@ -118,6 +148,56 @@ func TestFindBy(t *testing.T) {
 	t.Log("BY ID", u)
 }
 
+func TestFindWhere(t *testing.T) {
+	db := structDBU(t)
+	s := testStruct{}
+	if err := db.FindWhere(&s, "kind in (?, ?) and name like ?", 69, 42, "g%"); err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "ghi" {
+		t.Errorf("expected name ghi, got %q", s.Name)
+	}
+	u := testStruct{}
+	if err := db.FindWhere(&u, "kind in (?, ?)", 1000, 1001); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+// shortStruct is missing a member pointer for the modified column
+type shortStruct struct {
+	testStruct
+}
+
+func (s *shortStruct) MemberPointers() []interface{} {
+	return []interface{}{&s.ID, &s.Name, &s.Kind, &s.Data}
+}
+
+func TestColumnMismatch(t *testing.T) {
+	db := structDBU(t)
+	s := shortStruct{}
+	err := db.FindBy(&s, "id", 1)
+	if errors.Cause(err) != ErrColumnMismatch {
+		t.Fatalf("expected ErrColumnMismatch, got %v", err)
+	}
+	t.Log(err)
+}
+
+func TestUnknownColumn(t *testing.T) {
+	db := structDBU(t)
+	const evil = "1=1; drop table structs; --"
+	s := testStruct{}
+	if err := db.FindBy(&s, evil, 1); errors.Cause(err) != ErrUnknownColumn {
+		t.Errorf("expected ErrUnknownColumn, got %v", err)
+	}
+	m := map[string]interface{}{"kind": 2, evil: 1}
+	if err := db.Find(&s, m); errors.Cause(err) != ErrUnknownColumn {
+		t.Errorf("expected ErrUnknownColumn, got %v", err)
+	}
+	if count := countStructs(t, db); count == 0 {
+		t.Error("expected structs table to be intact")
+	}
+}
+
 func TestSelf(t *testing.T) {
 	db := structDBU(t)
 	s := testStruct{ID: 1}
@@ -155,6 +235,334 @@ func TestDBObject(t *testing.T) {
 	}
 }
 
+// naturalStruct has its key supplied by the caller
+type naturalStruct struct {
+	testStruct
+}
+
+func (s *naturalStruct) InsertFields() string {
+	return "id,name,kind,data"
+}
+
+func (s *naturalStruct) InsertValues() []interface{} {
+	return []interface{}{s.ID, s.Name, s.Kind, s.Data}
+}
+
+func (s *naturalStruct) AutoIncrement() bool {
+	return false
+}
+
+func TestNaturalKey(t *testing.T) {
+	db := structDBU(t)
+	s := &naturalStruct{}
+	s.ID = 1234
+	s.Name = "natural"
+	if err := db.Add(s); err != nil {
+		t.Fatal(err)
+	}
+	if s.ID != 1234 {
+		t.Fatalf("expected key 1234, got %d", s.ID)
+	}
+	u := testStruct{}
+	if err := db.FindByID(&u, 1234); err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != 1234 || u.Name != "natural" {
+		t.Errorf("unexpected record: %+v", u)
+	}
+}
+
+func countStructs(t *testing.T, db *DBU) int {
+	t.Helper()
+	var count int
+	if err := db.DB().QueryRow("select count(*) from structs").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestQueryTimeout(t *testing.T) {
+	db := structDBU(t)
+	const slow = "with recursive c(x) as (select 1 union all select x+1 from c where x < 1000000000) select count(*) from c"
+	db.SetQueryTimeout(50 * time.Millisecond)
+	var count int64
+	start := time.Now()
+	err := db.ScanValue(&count, slow)
+	if err == nil {
+		t.Fatal("expected the slow query to time out")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) && !strings.Contains(err.Error(), "interrupt") {
+		t.Errorf("expected a timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the query to be cancelled promptly, took %v", elapsed)
+	}
+	if _, _, err := db.Exec("insert into structs (name) select 'slow' from (" + slow + ")"); err == nil {
+		t.Error("expected the slow exec to time out")
+	}
+	// a cancelled transaction's connection may not be reused, so each gets its own database
+	for name, fn := range map[string]func(du *DBU) error{
+		"script": func(du *DBU) error {
+			return du.ExecScript("insert into structs (name) select 'slow' from (" + slow + ");")
+		},
+		"insert many": func(du *DBU) error {
+			return du.InsertMany("insert into structs (name) select ? from ("+slow+")", []interface{}{"slow"})
+		},
+		"migration": func(du *DBU) error {
+			return du.Migrate([]Migration{{Version: 1, Up: "insert into structs (name) select 'slow' from (" + slow + ")"}})
+		},
+	} {
+		du := structDBU(t)
+		du.SetQueryTimeout(50 * time.Millisecond)
+		if err := fn(du); !errors.Is(err, context.DeadlineExceeded) && (err == nil || !strings.Contains(err.Error(), "interrupt")) {
+			t.Errorf("%s: expected a timeout error, got %v", name, err)
+		}
+	}
+	db.SetQueryTimeout(0)
+	if err := db.ScanValue(&count, "select count(*) from structs"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	db := structDBU(t)
+	before := countStructs(t, db)
+	db.SetReadOnly(true)
+	if err := db.Add(&testStruct{Name: "read only"}); errors.Cause(err) != ErrReadOnly {
+		t.Errorf("expected ErrReadOnly on add, got %v", err)
+	}
+	if err := db.Save(&testStruct{ID: 1, Name: "read only"}); errors.Cause(err) != ErrReadOnly {
+		t.Errorf("expected ErrReadOnly on save, got %v", err)
+	}
+	if err := db.Delete(&testStruct{ID: 1}); errors.Cause(err) != ErrReadOnly {
+		t.Errorf("expected ErrReadOnly on delete, got %v", err)
+	}
+	if err := db.Replace(&testStruct{ID: 1, Name: "read only"}); errors.Cause(err) != ErrReadOnly {
+		t.Errorf("expected ErrReadOnly on replace, got %v", err)
+	}
+	if _, _, err := db.Exec("delete from structs"); err != ErrReadOnly {
+		t.Errorf("expected ErrReadOnly on exec, got %v", err)
+	}
+	if err := db.InsertMany("insert into structs (name) values (?)", []interface{}{"read only"}); err != ErrReadOnly {
+		t.Errorf("expected ErrReadOnly on insert many, got %v", err)
+	}
+	if after := countStructs(t, db); after != before {
+		t.Errorf("expected %d rows while read-only, got %d", before, after)
+	}
+	s := testStruct{}
+	if err := db.FindByID(&s, 1); err != nil || s.Name == "read only" {
+		t.Errorf("expected reads to proceed unchanged, got %+v (%v)", s, err)
+	}
+	db.SetReadOnly(false)
+	if err := db.Add(&testStruct{Name: "writable"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	db := structDBU(t)
+	before := countStructs(t, db)
+	db.SetDryRun(true)
+	s := &testStruct{Name: "dry", Kind: 1}
+	if err := db.Add(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(&testStruct{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if after := countStructs(t, db); after != before {
+		t.Fatalf("expected %d rows during dry run, got %d", before, after)
+	}
+	u := testStruct{}
+	if err := db.FindByID(&u, 1); err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != 1 {
+		t.Errorf("expected record 1 to remain, got %+v", u)
+	}
+	db.SetDryRun(false)
+	if err := db.Add(s); err != nil {
+		t.Fatal(err)
+	}
+	if after := countStructs(t, db); after != before+1 {
+		t.Fatalf("expected %d rows after dry run, got %d", before+1, after)
+	}
+}
+
+func TestAddReturning(t *testing.T) {
+	db := structDBU(t)
+	s := &testStruct{Name: "returning", Kind: 7}
+	if err := db.AddReturning(s); err != nil {
+		t.Fatal(err)
+	}
+	if s.ID == 0 {
+		t.Error("expected id to be set")
+	}
+	if s.Modified.IsZero() {
+		t.Error("expected modified timestamp to be populated")
+	}
+	t.Logf("RETURNED: %+v", s)
+}
+
+// returningDBS fakes a backend supporting insert ... returning,
+// recording the queries and returning the given id
+type returningDBS struct {
+	DBS
+	id      int64
+	queries []string
+}
+
+func (r *returningDBS) Query(fn SetHandler, query string, args ...interface{}) error {
+	r.queries = append(r.queries, query)
+	*fn()[0].(*int64) = r.id
+	return nil
+}
+
+func TestAddReturningGuards(t *testing.T) {
+	rec := &returningDBS{id: 42}
+	du := &DBU{dbs: rec, dialect: Postgres}
+	var ops []string
+	du.SetMetrics(func(op, query string, dur time.Duration, err error) {
+		ops = append(ops, op)
+	})
+	s := &testStruct{Name: "guarded", Kind: 8}
+	du.SetReadOnly(true)
+	if err := du.AddReturning(s); errors.Cause(err) != ErrReadOnly {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+	du.SetReadOnly(false)
+	du.SetDryRun(true)
+	if err := du.AddReturning(s); err != nil {
+		t.Fatal(err)
+	}
+	du.SetDryRun(false)
+	if len(rec.queries) != 0 || len(ops) != 0 || s.ID != 0 {
+		t.Errorf("expected no statements run, got %v", rec.queries)
+	}
+	if err := du.AddReturning(s); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.queries) != 1 || !strings.Contains(rec.queries[0], " returning ") {
+		t.Errorf("expected insert ... returning through the backend, got %v", rec.queries)
+	}
+	if s.ID != 42 {
+		t.Errorf("expected the returned id, got %d", s.ID)
+	}
+	if len(ops) != 1 || ops[0] != "exec" {
+		t.Errorf("expected the insert to be measured as an exec, got %v", ops)
+	}
+}
+
+func TestAddReturningMySQL(t *testing.T) {
+	db := structDBU(t)
+	rec := &recordingDBS{DBS: sqlWrapper{db: db.db}}
+	du := &DBU{dbs: rec, dialect: MySQL}
+	s := &testStruct{Name: "mysql", Kind: 10}
+	if err := du.AddReturning(s); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.execs) != 1 || strings.Contains(rec.execs[0], "returning") {
+		t.Errorf("expected a plain insert for MySQL, got %v", rec.execs)
+	}
+	if s.ID == 0 || s.Modified.IsZero() {
+		t.Errorf("expected the row to be found after the insert, got %+v", s)
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	for _, tc := range []struct {
+		version string
+		ok      bool
+	}{
+		{"3.32.2", false},
+		{"3.35.0", true},
+		{"3.40.1", true},
+		{"4.0", true},
+		{"bogus", false},
+	} {
+		if ok := versionAtLeast(tc.version, 3, 35); ok != tc.ok {
+			t.Errorf("version %s: expected %t, got %t", tc.version, tc.ok, ok)
+		}
+	}
+}
+
+// captureLogger records the logged messages
+type captureLogger struct {
+	msgs []string
+	args [][]interface{}
+}
+
+func (c *captureLogger) Debugf(msg string, args ...interface{}) {
+	c.msgs = append(c.msgs, msg)
+	c.args = append(c.args, args)
+}
+
+func TestLogger(t *testing.T) {
+	db := structDBU(t)
+	logger := &captureLogger{}
+	db.SetLogger(logger)
+	s := &testStruct{Name: "logged", Kind: 99}
+	if err := db.Add(s); err != nil {
+		t.Fatal(err)
+	}
+	if len(logger.args) == 0 {
+		t.Fatal("nothing was logged")
+	}
+	args := logger.args[0]
+	if len(args) != 2 {
+		t.Fatalf("expected query and args, got: %v", args)
+	}
+	if query, _ := insertQuery(s, SQLite); args[0].(string) != query {
+		t.Errorf("unexpected query logged: %s", args[0])
+	}
+	values := args[1].([]interface{})
+	if len(values) != 3 || values[0] != "logged" || values[1] != 99 {
+		t.Errorf("unexpected args logged: %v", values)
+	}
+	db.SetLogger(StdLogger{log.New(ioutil.Discard, "", 0)})
+	if err := db.Delete(s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// auditStruct writes the modified column on insert and update
+type auditStruct struct {
+	testStruct
+}
+
+func (s *auditStruct) InsertFields() string {
+	return "name,kind,data,modified"
+}
+
+func (s *auditStruct) InsertValues() []interface{} {
+	return []interface{}{s.Name, s.Kind, s.Data, s.Modified}
+}
+
+func (s *auditStruct) UpdateValues() []interface{} {
+	return []interface{}{s.Name, s.Kind, s.Data, s.Modified, s.ID}
+}
+
+func TestSaveAs(t *testing.T) {
+	db := structDBU(t)
+	then := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := &auditStruct{}
+	s.Name = "audited"
+	s.Modified = then
+	if err := db.Add(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveAs(s, 42); err != nil {
+		t.Fatal(err)
+	}
+	u := testStruct{}
+	if err := db.FindByID(&u, s.ID); err != nil {
+		t.Fatal(err)
+	}
+	if !u.Modified.After(then) {
+		t.Errorf("expected modified to be updated, got %v", u.Modified)
+	}
+}
+
 /*
 func testDBU(t *testing.T) *sql.DB {
 	return nil
@@ -235,6 +643,89 @@ func TestListQuery(t *testing.T) {
 	}
 }
 
+func TestListQueryArgs(t *testing.T) {
+	db := structDBU(t)
+	replica := structDBU(t)
+	if _, err := replica.DB().Exec("update structs set kind=kind+100 where id=?", 1); err != nil {
+		t.Fatal(err)
+	}
+	db.SetReadDB(replica.DB())
+	// a closed primary shows the list is read from the replica
+	if err := db.DB().Close(); err != nil {
+		t.Fatal(err)
+	}
+	list := new(_testStruct)
+	if err := db.ListQueryArgs(list, "kind > ?", 100); err != nil {
+		t.Fatal(err)
+	}
+	if len(*list) != 1 || (*list)[0].ID != 1 {
+		t.Errorf("expected only the replica's updated record, got %+v", *list)
+	}
+}
+
+func TestListAfter(t *testing.T) {
+	db := structDBU(t)
+	total := countStructs(t, db)
+	seen := make(map[int64]bool)
+	var after interface{}
+	pages := 0
+	for {
+		list := new(_testStruct)
+		if err := db.ListAfter(list, "id", after, 2); err != nil {
+			t.Fatal(err)
+		}
+		if len(*list) == 0 {
+			break
+		}
+		pages++
+		for _, item := range *list {
+			if seen[item.ID] {
+				t.Errorf("id %d listed twice", item.ID)
+			}
+			seen[item.ID] = true
+		}
+		after = (*list)[len(*list)-1].ID
+	}
+	if len(seen) != total {
+		t.Errorf("expected %d objects listed, got %d", total, len(seen))
+	}
+	if want := (total + 1) / 2; pages != want {
+		t.Errorf("expected %d pages, got %d", want, pages)
+	}
+	if err := db.ListAfter(new(_testStruct), "bogus", 0, 2); errors.Cause(err) != ErrUnknownColumn {
+		t.Errorf("expected ErrUnknownColumn, got %v", err)
+	}
+	if err := db.ListAfter(new(_testStruct), "id; drop table structs", 0, 2); errors.Cause(err) != ErrInvalidColumn {
+		t.Errorf("expected ErrInvalidColumn, got %v", err)
+	}
+}
+
+// shortList stops providing receivers after the first row
+type shortList struct {
+	_testStruct
+}
+
+func (list *shortList) Receivers() []interface{} {
+	if len(list._testStruct) > 0 {
+		return nil
+	}
+	return list._testStruct.Receivers()
+}
+
+func TestListNil(t *testing.T) {
+	db := structDBU(t)
+	if err := db.List(nil); err != ErrNilList {
+		t.Errorf("expected ErrNilList, got %v", err)
+	}
+	var list *_testStruct
+	if err := db.ListQuery(list, ""); err != ErrNilList {
+		t.Errorf("expected ErrNilList for nil pointer, got %v", err)
+	}
+	if err := db.ListQuery(new(shortList), ""); err != ErrNilWritePointers {
+		t.Errorf("expected ErrNilWritePointers, got %v", err)
+	}
+}
+
 func TestInsertMany(t *testing.T) {
 	db := structDBU(t)
 	query := "insert into structs(name, kind, data) values(?, ?, ?)"
@@ -258,3 +749,1435 @@ func TestInsertMany(t *testing.T) {
 		t.Logf("ITEM:  %+v\n", item)
 	}
 }
+
+// cancelValue cancels its context when it is converted for the driver
+type cancelValue struct {
+	cancel context.CancelFunc
+}
+
+func (c cancelValue) Value() (driver.Value, error) {
+	c.cancel()
+	return "cancelled", nil
+}
+
+func TestInsertManyContext(t *testing.T) {
+	// cancellation may discard the connection, so an in-memory database won't do
+	dir, err := ioutil.TempDir("", "dbobj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := NewDBU(filepath.Join(dir, "test.db"), true, sqlite.Open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	prepare(db.DB())
+	before := countStructs(t, db)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	query := "insert into structs(name, kind, data) values(?, ?, ?)"
+	values := make([][]interface{}, 1000)
+	for i := range values {
+		values[i] = []interface{}{fmt.Sprintf("name%d", i), i, "blah"}
+	}
+	values[500][2] = cancelValue{cancel}
+	if err := db.InsertManyContext(ctx, query, values...); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if after := countStructs(t, db); after != before {
+		t.Errorf("expected no rows committed, had %d now %d", before, after)
+	}
+}
+
+func TestSaveMany(t *testing.T) {
+	db := structDBU(t)
+	list := new(_testStruct)
+	if err := db.ListQuery(list, ""); err != nil {
+		t.Fatal(err)
+	}
+	objs := make([]DBObject, 0, len(*list))
+	for i := range *list {
+		item := &(*list)[i]
+		item.Name = fmt.Sprintf("saved %d", item.ID)
+		item.Kind = int(item.ID) * 10
+		objs = append(objs, item)
+	}
+	if err := db.SaveMany(objs...); err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range objs {
+		u := testStruct{}
+		if err := db.FindByID(&u, o.Key()); err != nil {
+			t.Fatal(err)
+		}
+		if u.Name != fmt.Sprintf("saved %d", u.ID) || u.Kind != int(u.ID)*10 {
+			t.Errorf("record not updated: %+v", u)
+		}
+	}
+}
+
+func TestSaveManyChecks(t *testing.T) {
+	db := structDBU(t)
+	var ops []string
+	db.SetMetrics(func(op, query string, dur time.Duration, err error) {
+		ops = append(ops, op)
+	})
+	// SQLite serializes all writes instead, and a single stripe puts
+	// every key on the same lock, which must only be taken once
+	db.dialect = Postgres
+	db.SetKeyLocks(1)
+	first, second := &validStruct{}, &validStruct{}
+	first.ID, first.Name = 1, "first"
+	second.ID, second.Name = 2, "much too long"
+	if err := db.SaveMany(first, second); errors.Cause(err) != ErrInvalid {
+		t.Fatalf("expected ErrInvalid, got %v", err)
+	}
+	if len(ops) != 0 {
+		t.Errorf("expected nothing executed for an invalid object, got %v", ops)
+	}
+	second.Name = "second"
+	done := make(chan error)
+	go func() {
+		done <- db.SaveMany(first, second)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SaveMany deadlocked on its key locks")
+	}
+	if len(ops) != 1 || ops[0] != "exec" {
+		t.Errorf("expected the transaction to be measured as an exec, got %v", ops)
+	}
+	u := testStruct{}
+	if err := db.FindByID(&u, 2); err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != "second" {
+		t.Errorf("expected the objects to be saved, got %q", u.Name)
+	}
+}
+
+func TestSaveManyMixed(t *testing.T) {
+	db := structDBU(t)
+	if err := db.SaveMany(&testStruct{ID: 1}, &otherTable{}); err == nil {
+		t.Fatal("expected error for mixed tables")
+	}
+}
+
+// otherTable is a testStruct stored in a different table
+type otherTable struct {
+	testStruct
+}
+
+func (s *otherTable) TableName() string {
+	return "others"
+}
+
+func TestPing(t *testing.T) {
+	db := structDBU(t)
+	if err := db.Ping(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if stats := db.Stats(); stats.OpenConnections == 0 {
+		t.Errorf("expected open connections, got %+v", stats)
+	}
+	db.DB().Close()
+	if err := db.Ping(context.Background()); err == nil {
+		t.Error("expected error pinging closed db")
+	}
+	db.Close()
+	if err := db.Ping(context.Background()); err != ErrClosed {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	db := structDBU(t)
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != ErrClosed {
+		t.Errorf("expected ErrClosed closing twice, got %v", err)
+	}
+	if err := db.Add(&testStruct{Name: "closed"}); errors.Cause(err) != ErrClosed {
+		t.Errorf("expected ErrClosed adding after close, got %v", err)
+	}
+}
+
+// closingDBS counts the closes of an alternate backend
+type closingDBS struct {
+	DBS
+	closes int
+}
+
+func (c *closingDBS) Close() error {
+	c.closes++
+	return nil
+}
+
+func TestCloseBackend(t *testing.T) {
+	db := structDBU(t)
+	dbs := &closingDBS{DBS: sqlWrapper{db: db.db}}
+	du := &DBU{dbs: dbs}
+	if err := du.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := du.Close(); err != ErrClosed {
+		t.Errorf("expected ErrClosed closing twice, got %v", err)
+	}
+	if dbs.closes != 1 {
+		t.Errorf("expected the backend to be closed once, got %d", dbs.closes)
+	}
+	if _, _, err := du.Exec("delete from structs"); err != ErrClosed {
+		t.Errorf("expected ErrClosed executing after close, got %v", err)
+	}
+}
+
+func TestSaveFields(t *testing.T) {
+	db := structDBU(t)
+	s := testStruct{}
+	if err := db.FindByID(&s, 2); err != nil {
+		t.Fatal(err)
+	}
+	kind := s.Kind
+	s.Name = "patched"
+	s.Kind = kind + 1000
+	if err := db.SaveFields(&s, "name"); err != nil {
+		t.Fatal(err)
+	}
+	u := testStruct{}
+	if err := db.FindByID(&u, 2); err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != "patched" {
+		t.Errorf("expected name to be saved, got %q", u.Name)
+	}
+	if u.Kind != kind {
+		t.Errorf("expected kind to be untouched (%d), got %d", kind, u.Kind)
+	}
+	if err := db.SaveFields(&s, "bogus"); errors.Cause(err) != ErrUnknownColumn {
+		t.Errorf("expected ErrUnknownColumn, got %v", err)
+	}
+	if err := db.SaveFields(&s, "modified"); errors.Cause(err) != ErrUnknownColumn {
+		t.Errorf("expected ErrUnknownColumn saving an update:\"false\" column, got %v", err)
+	}
+}
+
+func TestDeleteWhere(t *testing.T) {
+	db := structDBU(t)
+	before := countStructs(t, db)
+	count, err := db.DeleteWhere(&testStruct{}, "kind=?", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 rows deleted, got %d", count)
+	}
+	if after := countStructs(t, db); after != before-3 {
+		t.Errorf("expected %d rows remaining, got %d", before-3, after)
+	}
+	if _, err := db.DeleteWhere(&testStruct{}, " "); err != ErrNoWhere {
+		t.Errorf("expected ErrNoWhere, got %v", err)
+	}
+	count, err = db.DeleteAll(&testStruct{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != int64(before-3) {
+		t.Errorf("expected %d rows deleted, got %d", before-3, count)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dbobj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	opts := Options{
+		BusyTimeout: 5 * time.Second,
+		JournalMode: "WAL",
+		ForeignKeys: true,
+	}
+	db, err := NewDBUWithOptions(filepath.Join(dir, "test.db"), opts, sqlite.Open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var mode string
+	if err := db.DB().QueryRow("pragma journal_mode").Scan(&mode); err != nil {
+		t.Fatal(err)
+	}
+	if mode != "wal" {
+		t.Errorf("expected journal mode wal, got %q", mode)
+	}
+	// hold one connection so the pool opens another, which must have the options too
+	ctx := context.Background()
+	first, err := db.DB().Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	second, err := db.DB().Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	for i, conn := range []*sql.Conn{first, second} {
+		var fk, timeout int
+		if err := conn.QueryRowContext(ctx, "pragma foreign_keys").Scan(&fk); err != nil {
+			t.Fatal(err)
+		}
+		if err := conn.QueryRowContext(ctx, "pragma busy_timeout").Scan(&timeout); err != nil {
+			t.Fatal(err)
+		}
+		if fk != 1 || timeout != 5000 {
+			t.Errorf("connection %d: expected foreign keys on and busy timeout 5000, got %d and %d", i, fk, timeout)
+		}
+	}
+}
+
+func TestOptionsDSN(t *testing.T) {
+	opts := Options{BusyTimeout: time.Second, ForeignKeys: true}
+	for file, want := range map[string]string{
+		"test.db":              "test.db?_busy_timeout=1000&_foreign_keys=1",
+		"file:test.db?mode=rw": "file:test.db?mode=rw&_busy_timeout=1000&_foreign_keys=1",
+	} {
+		if got := opts.dsn(file); got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	}
+	if got := (Options{JournalMode: "WAL"}).dsn("test.db"); got != "test.db" {
+		t.Errorf("expected no parameters, got %q", got)
+	}
+}
+
+func TestFindColumns(t *testing.T) {
+	db := structDBU(t)
+	s := testStruct{}
+	if err := db.FindColumns(&s, []string{"name"}, "id", 3); err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "ghi" {
+		t.Errorf("expected name ghi, got %q", s.Name)
+	}
+	if s.ID != 0 || s.Kind != 0 || s.Data != "" {
+		t.Errorf("expected only name to be loaded, got %+v", s)
+	}
+	if err := db.FindColumns(&s, []string{"name", "bogus"}, "id", 3); errors.Cause(err) != ErrUnknownColumn {
+		t.Errorf("expected ErrUnknownColumn, got %v", err)
+	}
+	if err := db.FindColumns(&s, []string{"name"}, "id", 999); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestSaveIfChanged(t *testing.T) {
+	db := structDBU(t)
+	counter := &queryCounter{}
+	db.SetLogger(counter)
+	original := testStruct{}
+	if err := db.FindByID(&original, 1); err != nil {
+		t.Fatal(err)
+	}
+	current := original
+	if err := db.SaveIfChanged(&current, &original); err != nil {
+		t.Fatal(err)
+	}
+	if n := counter.count("update"); n != 0 {
+		t.Fatalf("expected no updates for unchanged object, got %d", n)
+	}
+	current.Name = "changed"
+	if err := db.SaveIfChanged(&current, &original); err != nil {
+		t.Fatal(err)
+	}
+	if n := counter.count("update"); n != 1 {
+		t.Fatalf("expected 1 update for changed object, got %d", n)
+	}
+}
+
+func TestSameValues(t *testing.T) {
+	a := auditStruct{}
+	b := auditStruct{}
+	a.Modified = time.Now()
+	b.Modified = a.Modified.UTC()
+	if !equal(&a, &b) {
+		t.Error("expected objects with the same instant to be equal")
+	}
+	b.Kind = 1
+	if equal(&a, &b) {
+		t.Error("expected objects with different kinds to differ")
+	}
+}
+
+func TestCount(t *testing.T) {
+	db := structDBU(t)
+	count, err := db.Count(&testStruct{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 6 {
+		t.Errorf("expected 6 records, got %d", count)
+	}
+	count, err = db.Count(&testStruct{}, "kind=?", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 records of kind 2, got %d", count)
+	}
+}
+
+func TestExists(t *testing.T) {
+	db := structDBU(t)
+	ok, err := db.Exists(&testStruct{}, "name=?", "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Error("expected abc to exist")
+	}
+	ok, err = db.Exists(&testStruct{}, "name=?", "nobody")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Error("expected nobody not to exist")
+	}
+}
+
+func TestReadDB(t *testing.T) {
+	db := structDBU(t)
+	replica := structDBU(t)
+	if _, err := replica.DB().Exec("delete from structs where id > 1"); err != nil {
+		t.Fatal(err)
+	}
+	db.SetReadDB(replica.DB())
+
+	count, err := db.Count(&testStruct{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("expected read from replica with 1 record, got %d", count)
+	}
+	s := testStruct{}
+	if err := db.FindBy(&s, "name", "def"); err != nil {
+		t.Fatal(err)
+	}
+	if s.ID != 0 {
+		t.Errorf("expected def to be missing from replica, got %+v", s)
+	}
+
+	if err := db.Add(&testStruct{Name: "primary"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := countStructs(t, db); n != 7 {
+		t.Errorf("expected write to primary with 7 records, got %d", n)
+	}
+	if ok, err := db.Exists(&testStruct{}, "name=?", "primary"); err != nil || ok {
+		t.Errorf("expected write not to reach replica, got %v (%v)", ok, err)
+	}
+
+	db.SetReadDB(nil)
+	if count, _ = db.Count(&testStruct{}, ""); count != 7 {
+		t.Errorf("expected read from primary with 7 records, got %d", count)
+	}
+}
+
+func TestQueryError(t *testing.T) {
+	db := structDBU(t)
+	if _, err := db.DB().Exec("drop table structs"); err != nil {
+		t.Fatal(err)
+	}
+	check := func(op string, err error) {
+		t.Helper()
+		var qe QueryError
+		if !errors.As(err, &qe) {
+			t.Fatalf("%s: expected QueryError, got %T: %v", op, err, err)
+		}
+		if qe.Table != "structs" {
+			t.Errorf("%s: expected table structs, got %q", op, qe.Table)
+		}
+		if qe.Query == "" {
+			t.Errorf("%s: expected query to be set", op)
+		}
+	}
+	s := &testStruct{ID: 1, Name: "abc"}
+	check("add", db.Add(s))
+	check("save", db.Save(s))
+	check("delete", db.Delete(s))
+	check("find", db.FindBy(s, "name", "abc"))
+}
+
+// validStruct limits the length of its name
+type validStruct struct {
+	testStruct
+}
+
+func (s *validStruct) Validate() error {
+	if len(s.Name) > 8 {
+		return Invalid(s.TableName(), "name", "exceeds max length 8")
+	}
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	db := structDBU(t)
+	counter := &queryCounter{}
+	db.SetLogger(counter)
+	s := &validStruct{}
+	s.Name = "much too long"
+	if err := db.Add(s); errors.Cause(err) != ErrInvalid {
+		t.Fatalf("expected ErrInvalid, got %v", err)
+	}
+	if n := counter.count("insert"); n != 0 {
+		t.Fatalf("expected no inserts for invalid object, got %d", n)
+	}
+	s.Name = "short"
+	if err := db.Add(s); err != nil {
+		t.Fatal(err)
+	}
+	s.Name = "much too long"
+	if err := db.Save(s); errors.Cause(err) != ErrInvalid {
+		t.Fatalf("expected ErrInvalid, got %v", err)
+	}
+}
+
+func TestScanValue(t *testing.T) {
+	db := structDBU(t)
+	var max int64
+	if err := db.ScanValue(&max, "select max(id) from structs"); err != nil {
+		t.Fatal(err)
+	}
+	if max != 6 {
+		t.Errorf("expected max id 6, got %d", max)
+	}
+	var name string
+	if err := db.ScanValue(&name, "select name from structs where kind=? order by id", 2); err != nil {
+		t.Fatal(err)
+	}
+	if name != "jkl" {
+		t.Errorf("expected first name jkl, got %q", name)
+	}
+	if err := db.ScanValue(&name, "select name from structs where id=?", 999); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestQueryMaps(t *testing.T) {
+	db := structDBU(t)
+	kinds, err := db.QueryIntMap("select kind, count(*) from structs group by kind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int64]int64{2: 3, 23: 1, 42: 1, 69: 1}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("expected counts by kind %v, got %v", want, kinds)
+	}
+	names, err := db.QueryStringMap("select data, count(*) from structs where kind=? group by data", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantNames := map[string]int64{"of a kind": 1, "of a drag": 1, "of a sort": 1}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("expected counts by data %v, got %v", wantNames, names)
+	}
+	empty, err := db.QueryIntMap("select kind, count(*) from structs where kind < 0 group by kind")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(empty) != 0 {
+		t.Errorf("expected no counts, got %v", empty)
+	}
+	if _, err := db.QueryIntMap("select kind from structs"); errors.Cause(err) != ErrColumnMismatch {
+		t.Errorf("expected ErrColumnMismatch, got %v", err)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	db := structDBU(t)
+	if err := db.Truncate(&testStruct{}); err != nil {
+		t.Fatal(err)
+	}
+	count, err := db.Count(&testStruct{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("expected no records after truncate, got %d", count)
+	}
+}
+
+func TestTruncateResetSequence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dbobj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := NewDBUWithOptions(filepath.Join(dir, "test.db"), Options{ResetSequence: true}, sqlite.Open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	const create = `create table others (
+    id integer primary key autoincrement,
+    name text,
+    kind int,
+    data blob,
+    modified   DATETIME DEFAULT CURRENT_TIMESTAMP
+);`
+	if _, err := db.DB().Exec(create); err != nil {
+		t.Fatal(err)
+	}
+	add := func() int64 {
+		t.Helper()
+		o := &otherTable{}
+		if err := db.Add(o); err != nil {
+			t.Fatal(err)
+		}
+		return o.ID
+	}
+	add()
+	add()
+	if err := db.Truncate(&otherTable{}); err != nil {
+		t.Fatal(err)
+	}
+	if id := add(); id != 1 {
+		t.Errorf("expected sequence reset to id 1, got %d", id)
+	}
+
+	db.resetSequence = false
+	add()
+	if err := db.Truncate(&otherTable{}); err != nil {
+		t.Fatal(err)
+	}
+	if id := add(); id != 3 {
+		t.Errorf("expected sequence to continue at id 3, got %d", id)
+	}
+}
+
+func TestFindJoined(t *testing.T) {
+	db := structDBU(t)
+	if _, err := db.DB().Exec(strings.Replace(queryCreate, "structs", "others", 1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.DB().Exec("insert into others (id, name, kind, data) values(23, 'other', 99, 'x')"); err != nil {
+		t.Fatal(err)
+	}
+	s := &testStruct{}
+	o := &otherTable{}
+	objs := []DBObject{s, o}
+	const join = "join others on others.id=structs.kind"
+	const want = "select structs.id,structs.name,structs.kind,structs.data,structs.modified," +
+		"others.id,others.name,others.kind,others.data,others.modified from structs " + join + " where structs.id=?"
+	if query := joinQuery(SQLite, objs, join, "structs.id=?"); query != want {
+		t.Errorf("expected query %q, got %q", want, query)
+	}
+	if err := db.FindJoined(objs, join, "structs.id=?", 1); err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "abc" || o.Name != "other" || o.Kind != 99 {
+		t.Errorf("unexpected join results: %+v %+v", s, o)
+	}
+	if err := db.FindJoined(nil, join, ""); err != ErrNoObjects {
+		t.Errorf("expected ErrNoObjects, got %v", err)
+	}
+}
+
+func TestNullModified(t *testing.T) {
+	db := structDBU(t)
+	result, err := db.DB().Exec("insert into structs(name, kind, data, modified) values('null', 1, 'x', NULL)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := result.LastInsertId()
+	s := testStruct{}
+	if err := db.FindByID(&s, id); err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "null" || !s.Modified.IsZero() {
+		t.Errorf("expected zero modified time, got %+v", s)
+	}
+}
+
+// columnStruct lists its columns, as generated objects do
+type columnStruct struct {
+	testStruct
+}
+
+func (s *columnStruct) Columns() []string {
+	return []string{"id", "name", "kind", "data"}
+}
+
+func TestColumns(t *testing.T) {
+	const insert = "insert into structs (name,kind,data) values(?,?,?)"
+	if query, _ := insertQuery(&columnStruct{}, SQLite); query != insert {
+		t.Errorf("expected %q, got %q", insert, query)
+	}
+	const update = "update structs set name=?,kind=?,data=? where id=?"
+	if query := updateQuery(&columnStruct{}, SQLite); query != update {
+		t.Errorf("expected %q, got %q", update, query)
+	}
+	if err := validColumn(&columnStruct{}, "modified"); errors.Cause(err) != ErrUnknownColumn {
+		t.Errorf("expected modified to be unknown, got %v", err)
+	}
+}
+
+func TestFindSelfErrors(t *testing.T) {
+	db := structDBU(t)
+	if err := db.FindSelf(&testStruct{}); err != ErrKeyMissing {
+		t.Errorf("expected ErrKeyMissing, got %v", err)
+	}
+	if err := db.FindSelf(&keylessStruct{}); err != ErrNoKeyField {
+		t.Errorf("expected ErrNoKeyField, got %v", err)
+	}
+	if err := db.FindSelf(&testStruct{ID: 999}); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := db.FindByID(&testStruct{}, 999); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+// keylessStruct is a testStruct without a key field
+type keylessStruct struct {
+	testStruct
+}
+
+func (s *keylessStruct) KeyField() string {
+	return ""
+}
+
+func TestSaveDeleteN(t *testing.T) {
+	db := structDBU(t)
+	s := &testStruct{ID: 1, Name: "updated"}
+	if n, err := db.SaveN(s); err != nil || n != 1 {
+		t.Errorf("expected 1 row saved, got %d (%v)", n, err)
+	}
+	missing := &testStruct{ID: 999, Name: "missing"}
+	if n, err := db.SaveN(missing); err != nil || n != 0 {
+		t.Errorf("expected 0 rows saved, got %d (%v)", n, err)
+	}
+	if n, err := db.DeleteN(s); err != nil || n != 1 {
+		t.Errorf("expected 1 row deleted, got %d (%v)", n, err)
+	}
+	if n, err := db.DeleteN(s); err != nil || n != 0 {
+		t.Errorf("expected 0 rows deleted for already deleted key, got %d (%v)", n, err)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	db := structDBU(t)
+	before := countStructs(t, db)
+	s := &testStruct{ID: 1, Name: "replaced", Kind: 7}
+	if err := db.Replace(s); err != nil {
+		t.Fatal(err)
+	}
+	if after := countStructs(t, db); after != before {
+		t.Errorf("expected %d rows after replace, got %d", before, after)
+	}
+	u := testStruct{}
+	if err := db.FindByID(&u, 1); err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != "replaced" || u.Kind != 7 {
+		t.Errorf("expected row 1 to be replaced, got %+v", u)
+	}
+	added := &testStruct{Name: "new"}
+	if err := db.Replace(added); err != nil {
+		t.Fatal(err)
+	}
+	if added.ID == 0 {
+		t.Error("expected replace of a new object to set its id")
+	}
+	if after := countStructs(t, db); after != before+1 {
+		t.Errorf("expected %d rows after replacing a new object, got %d", before+1, after)
+	}
+}
+
+func TestAddIgnore(t *testing.T) {
+	db := structDBU(t)
+	s := &naturalStruct{}
+	s.ID = 4321
+	s.Name = "seed"
+	added, err := db.AddIgnore(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !added {
+		t.Error("expected first insert to add a row")
+	}
+	s.Name = "reseed"
+	added, err = db.AddIgnore(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added {
+		t.Error("expected duplicate insert to be ignored")
+	}
+	u := testStruct{}
+	if err := db.FindByID(&u, 4321); err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != "seed" {
+		t.Errorf("expected original name, got %q", u.Name)
+	}
+}
+
+func TestIgnoreQuery(t *testing.T) {
+	s := &naturalStruct{}
+	for d, want := range map[Dialect]string{
+		SQLite:   "insert or ignore into structs (id,name,kind,data) values(?,?,?,?)",
+		Postgres: "insert into structs (id,name,kind,data) values($1,$2,$3,$4) on conflict do nothing",
+		MySQL:    "insert ignore into structs (id,name,kind,data) values(?,?,?,?)",
+	} {
+		if got, _ := ignoreQuery(s, d); got != want {
+			t.Errorf("%s: expected %q, got %q", d, want, got)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	db := structDBU(t)
+	var ops []string
+	var elapsed time.Duration
+	db.SetMetrics(func(op, query string, dur time.Duration, err error) {
+		if err != nil {
+			t.Errorf("unexpected error for %s: %v", query, err)
+		}
+		ops = append(ops, op)
+		elapsed += dur
+	})
+	s := testStruct{Name: "timed", Kind: 3}
+	if err := db.Add(&s); err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 1 || ops[0] != "exec" {
+		t.Fatalf("expected a single exec, got %v", ops)
+	}
+	if elapsed <= 0 {
+		t.Errorf("expected a positive duration, got %v", elapsed)
+	}
+	if err := db.FindSelf(&s); err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 2 || ops[1] != "query" {
+		t.Errorf("expected a query after the exec, got %v", ops)
+	}
+}
+
+// memberStruct is keyed by both its group and user
+type memberStruct struct {
+	GroupID int64
+	UserID  int64
+	Role    string
+}
+
+func (m *memberStruct) TableName() string           { return "members" }
+func (m *memberStruct) KeyField() string            { return "group_id" }
+func (m *memberStruct) KeyName() string             { return "GroupID" }
+func (m *memberStruct) Names() []string             { return []string{"GroupID", "UserID", "Role"} }
+func (m *memberStruct) SelectFields() string        { return "group_id,user_id,role" }
+func (m *memberStruct) InsertFields() string        { return "group_id,user_id,role" }
+func (m *memberStruct) Key() int64                  { return m.GroupID }
+func (m *memberStruct) SetID(id int64)              { m.GroupID = id }
+func (m *memberStruct) AutoIncrement() bool         { return false }
+func (m *memberStruct) ModifiedBy(int64, time.Time) {}
+
+func (m *memberStruct) InsertValues() []interface{} {
+	return []interface{}{m.GroupID, m.UserID, m.Role}
+}
+
+func (m *memberStruct) UpdateValues() []interface{} {
+	return []interface{}{m.UserID, m.Role, m.GroupID}
+}
+
+func (m *memberStruct) MemberPointers() []interface{} {
+	return []interface{}{&m.GroupID, &m.UserID, &m.Role}
+}
+
+func (m *memberStruct) FieldMap() map[string]interface{} {
+	return map[string]interface{}{"group_id": &m.GroupID, "user_id": &m.UserID, "role": &m.Role}
+}
+
+func (m *memberStruct) KeyFields() []string {
+	return []string{"group_id", "user_id"}
+}
+
+func (m *memberStruct) KeyValues() []interface{} {
+	return []interface{}{m.GroupID, m.UserID}
+}
+
+// codeStruct has a text key
+type codeStruct struct {
+	Code string
+	Name string
+}
+
+func (c *codeStruct) TableName() string           { return "codes" }
+func (c *codeStruct) KeyField() string            { return "code" }
+func (c *codeStruct) KeyName() string             { return "Code" }
+func (c *codeStruct) Names() []string             { return []string{"Code", "Name"} }
+func (c *codeStruct) SelectFields() string        { return "code,name" }
+func (c *codeStruct) InsertFields() string        { return "code,name" }
+func (c *codeStruct) Key() int64                  { return 0 }
+func (c *codeStruct) SetID(id int64)              {}
+func (c *codeStruct) AutoIncrement() bool         { return false }
+func (c *codeStruct) ModifiedBy(int64, time.Time) {}
+func (c *codeStruct) InsertValues() []interface{} { return []interface{}{c.Code, c.Name} }
+func (c *codeStruct) UpdateValues() []interface{} { return []interface{}{c.Name, c.Code} }
+func (c *codeStruct) KeyFields() []string         { return []string{"code"} }
+func (c *codeStruct) KeyValues() []interface{}    { return []interface{}{c.Code} }
+
+func (c *codeStruct) MemberPointers() []interface{} {
+	return []interface{}{&c.Code, &c.Name}
+}
+
+func (c *codeStruct) FieldMap() map[string]interface{} {
+	return map[string]interface{}{"code": &c.Code, "name": &c.Name}
+}
+
+func TestDeleteKeyValues(t *testing.T) {
+	db := structDBU(t)
+	for _, create := range []string{
+		"create table members (group_id int, user_id int, role text, primary key (group_id, user_id))",
+		"create table codes (code text primary key, name text)",
+	} {
+		if _, _, err := db.Exec(create); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, m := range []memberStruct{{1, 1, "owner"}, {1, 2, "member"}, {2, 1, "member"}} {
+		m := m
+		if err := db.Add(&m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deleted, err := db.DeleteN(&memberStruct{GroupID: 1, UserID: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 member deleted, got %d", deleted)
+	}
+	if ok, _ := db.Exists(&memberStruct{}, "group_id=? and user_id=?", 1, 1); !ok {
+		t.Error("expected the other member of the group to remain")
+	}
+
+	for _, c := range []codeStruct{{"abc", "first"}, {"def", "second"}} {
+		c := c
+		if err := db.Add(&c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Delete(&codeStruct{Code: "abc"}); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := db.Count(&codeStruct{}, ""); count != 1 {
+		t.Errorf("expected 1 code left, got %d", count)
+	}
+	if ok, _ := db.Exists(&codeStruct{}, "code=?", "def"); !ok {
+		t.Error("expected code def to remain")
+	}
+}
+
+func TestDeleteByKeys(t *testing.T) {
+	db := structDBU(t)
+	const create = "create table members (group_id int, user_id int, role text, primary key (group_id, user_id))"
+	if _, _, err := db.Exec(create); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []memberStruct{{1, 1, "owner"}, {1, 2, "member"}, {2, 1, "member"}} {
+		m := m
+		if err := db.Add(&m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deleted, err := db.DeleteByKeys(&memberStruct{}, map[string]interface{}{"user_id": 1, "group_id": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 row deleted, got %d", deleted)
+	}
+	if count, _ := db.Count(&memberStruct{}, ""); count != 2 {
+		t.Errorf("expected 2 members left, got %d", count)
+	}
+	if _, err := db.DeleteByKeys(&memberStruct{}, nil); err != ErrNoWhere {
+		t.Errorf("expected ErrNoWhere, got %v", err)
+	}
+	if _, err := db.DeleteByKeys(&memberStruct{}, map[string]interface{}{"bogus": 1}); errors.Cause(err) != ErrUnknownColumn {
+		t.Errorf("expected ErrUnknownColumn, got %v", err)
+	}
+}
+
+// patchStruct builds its partial updates as dbgen would
+type patchStruct struct {
+	testStruct
+	patched []string
+}
+
+func (s *patchStruct) UpdateFieldQuery(d Dialect, table string, cols ...string) (string, []interface{}, error) {
+	s.patched = cols
+	query := "update " + table + " set "
+	args := make([]interface{}, 0, len(cols)+1)
+	for i, col := range cols {
+		switch col {
+		case "name":
+			args = append(args, s.Name)
+		case "kind":
+			args = append(args, s.Kind)
+		case "data":
+			args = append(args, s.Data)
+		case "modified":
+			args = append(args, NullableTime(s.Modified))
+		default:
+			return "", nil, UnknownColumn(table, col)
+		}
+		if i > 0 {
+			query += ","
+		}
+		query += col + "=" + d.Placeholder(len(args))
+	}
+	return query + " where id=" + d.Placeholder(len(args)+1), append(args, s.ID), nil
+}
+
+func TestPatch(t *testing.T) {
+	db := structDBU(t)
+	original := testStruct{}
+	if err := db.FindByID(&original, 1); err != nil {
+		t.Fatal(err)
+	}
+	s := &patchStruct{testStruct: original}
+	s.Name = "patched"
+	s.Kind = 77
+	s.Data = "not saved"
+	if err := db.Patch(s, "name", "kind"); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.patched) != 2 {
+		t.Errorf("expected generated query to be used, got %v", s.patched)
+	}
+	u := testStruct{}
+	if err := db.FindByID(&u, 1); err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != "patched" || u.Kind != 77 {
+		t.Errorf("expected patched columns to be saved: %+v", u)
+	}
+	if u.Data != original.Data || !u.Modified.Equal(original.Modified) {
+		t.Errorf("expected other columns unchanged: %+v vs %+v", u, original)
+	}
+	if err := db.Patch(s, "bogus"); errors.Cause(err) != ErrUnknownColumn {
+		t.Errorf("expected ErrUnknownColumn, got %v", err)
+	}
+	if err := db.Patch(&original, "data"); err != nil {
+		t.Errorf("expected fallback to SaveFields, got %v", err)
+	}
+	for _, o := range []DBObject{s, &original} {
+		if err := db.Patch(o, "name", "modified"); errors.Cause(err) != ErrUnknownColumn {
+			t.Errorf("%T: expected ErrUnknownColumn patching an update:\"false\" column, got %v", o, err)
+		}
+	}
+}
+
+func TestFindByIDs(t *testing.T) {
+	db := structDBU(t)
+	list, err := db.FindByIDs(&testStruct{}, []interface{}{3, 1, 99})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected 2 objects, got %d: %v", len(list), list)
+	}
+	for i, id := range []int64{3, 1} {
+		s, ok := list[i].(*testStruct)
+		if !ok {
+			t.Fatalf("expected *testStruct, got %T", list[i])
+		}
+		if s.ID != id {
+			t.Errorf("expected id %d at %d, got %d", id, i, s.ID)
+		}
+	}
+	if list[0] == list[1] {
+		t.Error("expected distinct objects")
+	}
+	if list, err = db.FindByIDs(&testStruct{}, nil); err != nil || len(list) != 0 {
+		t.Errorf("expected no objects, got %v, %v", list, err)
+	}
+}
+
+func TestFindByIDsStringKey(t *testing.T) {
+	db := structDBU(t)
+	if _, _, err := db.Exec("create table codes (code text primary key, name text)"); err != nil {
+		t.Fatal(err)
+	}
+	for _, code := range []string{"abc", "def"} {
+		if err := db.Add(&codeStruct{Code: code, Name: "name " + code}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list, err := db.FindByIDs(&codeStruct{}, []interface{}{"def", "xyz", "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected 2 objects, got %d: %v", len(list), list)
+	}
+	for i, code := range []string{"def", "abc"} {
+		if c := list[i].(*codeStruct); c.Code != code {
+			t.Errorf("expected code %s at %d, got %+v", code, i, c)
+		}
+	}
+}
+
+// defaultStruct leaves a zero kind out of inserts, as dbgen does for omitempty
+type defaultStruct struct {
+	testStruct
+}
+
+func (s *defaultStruct) TableName() string {
+	return "defaults"
+}
+
+func (s *defaultStruct) InsertValues() []interface{} {
+	return []interface{}{s.Name, OmitEmpty(s.Kind), s.Data}
+}
+
+// nullStruct inserts its data as an explicit NULL
+type nullStruct struct {
+	defaultStruct
+}
+
+func (s *nullStruct) InsertValues() []interface{} {
+	return []interface{}{s.Name, OmitEmpty(s.Kind), nil}
+}
+
+func TestOmitEmptyDefault(t *testing.T) {
+	db := structDBU(t)
+	const create = "create table defaults (id integer not null primary key, name text, kind int not null default 7, data blob default 'none', modified datetime)"
+	if _, _, err := db.Exec(create); err != nil {
+		t.Fatal(err)
+	}
+	for _, kind := range []int{0, 3} {
+		s := &defaultStruct{}
+		s.Name = "default"
+		s.Kind = kind
+		if err := db.Add(s); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.FindSelf(s); err != nil {
+			t.Fatal(err)
+		}
+		want := kind
+		if kind == 0 {
+			want = 7
+		}
+		if s.Kind != want {
+			t.Errorf("expected kind %d for %d, got %d", want, kind, s.Kind)
+		}
+	}
+	// nil is NULL, not the column's default
+	s := &nullStruct{}
+	s.Name = "null"
+	if err := db.Add(s); err != nil {
+		t.Fatal(err)
+	}
+	var isNull bool
+	if err := db.ScanValue(&isNull, "select data is null from defaults where id=?", s.ID); err != nil {
+		t.Fatal(err)
+	}
+	if !isNull {
+		t.Error("expected a nil insert value to be NULL rather than the default")
+	}
+}
+
+// shardStruct is stored in a table per kind
+type shardStruct struct {
+	testStruct
+}
+
+func (s *shardStruct) DynamicTable() string {
+	return fmt.Sprintf("structs_%d", s.Kind)
+}
+
+// shardPatchStruct is a shardStruct with generated partial updates
+type shardPatchStruct struct {
+	patchStruct
+}
+
+func (s *shardPatchStruct) DynamicTable() string {
+	return fmt.Sprintf("structs_%d", s.Kind)
+}
+
+func TestDynamicTable(t *testing.T) {
+	db := structDBU(t)
+	for _, shard := range []string{"structs_1", "structs_2"} {
+		if _, _, err := db.Exec(strings.Replace(queryCreate, "structs", shard, 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, kind := range []int{1, 2, 2} {
+		s := &shardStruct{}
+		s.Name = "event"
+		s.Kind = kind
+		if err := db.Add(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for shard, want := range map[string]int64{"structs_1": 1, "structs_2": 2} {
+		var count int64
+		if err := db.ScanValue(&count, "select count(*) from "+shard); err != nil {
+			t.Fatal(err)
+		}
+		if count != want {
+			t.Errorf("expected %d rows in %s, got %d", want, shard, count)
+		}
+	}
+	s := &shardStruct{}
+	s.Kind = 2
+	if err := db.Find(s, map[string]interface{}{"id": 2}); err != nil {
+		t.Fatal(err)
+	}
+	s.Name = "updated"
+	if err := db.Save(s); err != nil {
+		t.Fatal(err)
+	}
+	p := &shardPatchStruct{patchStruct{testStruct: s.testStruct}}
+	p.Name = "patched"
+	if err := db.Patch(p, "name"); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.patched) != 1 {
+		t.Errorf("expected generated query to be used, got %v", p.patched)
+	}
+	var name string
+	if err := db.ScanValue(&name, "select name from structs_2 where id=2"); err != nil {
+		t.Fatal(err)
+	}
+	if name != "patched" {
+		t.Errorf("expected the shard to be patched, got %q", name)
+	}
+	if err := db.Delete(s); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := db.Count(s, ""); count != 1 {
+		t.Errorf("expected 1 row left in the shard, got %d", count)
+	}
+	if count, _ := db.Count(&testStruct{}, ""); count != 6 {
+		t.Errorf("expected the base table untouched, got %d rows", count)
+	}
+}
+
+// orderStruct has a table and a column named for reserved words
+type orderStruct struct {
+	ID    int64
+	Name  string
+	Order int
+}
+
+func (o *orderStruct) TableName() string             { return "group" }
+func (o *orderStruct) KeyField() string              { return "id" }
+func (o *orderStruct) KeyName() string               { return "ID" }
+func (o *orderStruct) Names() []string               { return []string{"ID", "Name", "Order"} }
+func (o *orderStruct) SelectFields() string          { return "id,name,order" }
+func (o *orderStruct) InsertFields() string          { return "name,order" }
+func (o *orderStruct) Key() int64                    { return o.ID }
+func (o *orderStruct) SetID(id int64)                { o.ID = id }
+func (o *orderStruct) ModifiedBy(int64, time.Time)   {}
+func (o *orderStruct) InsertValues() []interface{}   { return []interface{}{o.Name, o.Order} }
+func (o *orderStruct) UpdateValues() []interface{}   { return []interface{}{o.Name, o.Order, o.ID} }
+func (o *orderStruct) MemberPointers() []interface{} { return []interface{}{&o.ID, &o.Name, &o.Order} }
+
+func (o *orderStruct) FieldMap() map[string]interface{} {
+	return map[string]interface{}{"id": &o.ID, "name": &o.Name, "order": &o.Order}
+}
+
+func TestReservedColumn(t *testing.T) {
+	db := structDBU(t)
+	if _, _, err := db.Exec(`create table "group" (id integer primary key, name text, "order" int)`); err != nil {
+		t.Fatal(err)
+	}
+	o := &orderStruct{Name: "first", Order: 1}
+	if err := db.Add(o); err != nil {
+		t.Fatal(err)
+	}
+	o.Order = 2
+	if err := db.Save(o); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveFields(o, "order"); err != nil {
+		t.Fatal(err)
+	}
+	got := &orderStruct{}
+	if err := db.FindByID(got, o.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got.Order != 2 {
+		t.Errorf("expected order 2, got %+v", got)
+	}
+	if err := db.Find(got, map[string]interface{}{"order": 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.FindBy(got, "order", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.FindCond(got, NewWhere().Eq("order", 2).In("order", 1, 2)); err != nil {
+		t.Fatal(err)
+	}
+	cols := &orderStruct{}
+	if err := db.FindColumns(cols, []string{"order"}, "id", o.ID); err != nil {
+		t.Fatal(err)
+	}
+	if cols.Order != 2 {
+		t.Errorf("expected order 2, got %+v", cols)
+	}
+	if err := db.Add(&orderStruct{Name: "second", Order: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := db.Count(o, ""); err != nil || count != 2 {
+		t.Errorf("expected 2 rows, got %d (%v)", count, err)
+	}
+	if ok, err := db.Exists(o, ""); err != nil || !ok {
+		t.Errorf("expected rows to exist, got %t (%v)", ok, err)
+	}
+	it, err := db.Iterate(o, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := 0
+	for it.Next() {
+		if err := it.Scan(&orderStruct{}); err != nil {
+			t.Fatal(err)
+		}
+		rows++
+	}
+	it.Close()
+	if rows != 2 {
+		t.Errorf("expected to iterate 2 rows, got %d", rows)
+	}
+	list, err := NewRepo[orderStruct](db).List("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Errorf("expected to list 2 rows, got %d", len(list))
+	}
+	if err := db.FindJoined([]DBObject{cols}, "", `"group".id=?`, o.ID); err != nil {
+		t.Fatal(err)
+	}
+	if cols.Name != "first" {
+		t.Errorf("expected the joined row, got %+v", cols)
+	}
+	if n, err := db.DeleteWhere(o, `"order"=?`, 3); err != nil || n != 1 {
+		t.Errorf("expected to delete 1 row, got %d (%v)", n, err)
+	}
+	if err := db.Delete(o); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.FindByID(got, o.ID); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+	if err := db.Add(&orderStruct{Name: "third", Order: 4}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := db.DeleteAll(o); err != nil || n != 1 {
+		t.Errorf("expected to delete 1 row, got %d (%v)", n, err)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	for d, want := range map[Dialect]string{SQLite: `"order"`, Postgres: `"order"`, MySQL: "`order`"} {
+		if got := d.Quote("order"); got != want {
+			t.Errorf("%s: expected %s, got %s", d, want, got)
+		}
+		if got := d.Quote("name"); got != "name" {
+			t.Errorf("%s: expected name unquoted, got %s", d, got)
+		}
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	db := structDBU(t)
+	db.EnableCache(time.Minute)
+	s := &testStruct{}
+	if err := db.FindByID(s, 4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.DB().Exec("update structs set name=?, kind=? where id=?", "external", 404, 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Refresh(s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "external" || s.Kind != 404 {
+		t.Errorf("expected refreshed values, got %+v", s)
+	}
+	if _, err := db.DB().Exec("delete from structs where id=?", 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Refresh(s); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := db.Refresh(&testStruct{}); err != ErrKeyMissing {
+		t.Errorf("expected ErrKeyMissing, got %v", err)
+	}
+	if _, _, err := db.Exec("create table codes (code text primary key, name text)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Add(&codeStruct{Code: "US", Name: "United States"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.DB().Exec("update codes set name=? where code=?", "USA", "US"); err != nil {
+		t.Fatal(err)
+	}
+	c := &codeStruct{Code: "US"}
+	if err := db.Refresh(c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "USA" {
+		t.Errorf("expected refreshed name by string key, got %q", c.Name)
+	}
+	if err := db.Refresh(&codeStruct{}); err != ErrKeyMissing {
+		t.Errorf("expected ErrKeyMissing for an empty string key, got %v", err)
+	}
+}
+
+// recordingDBS records the statements sent through the DBS interface
+type recordingDBS struct {
+	DBS
+	execs []string
+}
+
+func (r *recordingDBS) Exec(query string, args ...interface{}) (int64, int64, error) {
+	r.execs = append(r.execs, query)
+	return r.DBS.Exec(query, args...)
+}
+
+func TestDBSWrites(t *testing.T) {
+	db := structDBU(t)
+	rec := &recordingDBS{DBS: sqlWrapper{db: db.db}}
+	du := &DBU{dbs: rec}
+	s := testStruct{Name: "dbs", Kind: 5}
+	if err := du.Add(&s); err != nil {
+		t.Fatal(err)
+	}
+	s.Kind = 6
+	if err := du.Save(&s); err != nil {
+		t.Fatal(err)
+	}
+	if err := du.Delete(&s); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.execs) != 3 {
+		t.Errorf("expected 3 statements through the DBS, got %d: %v", len(rec.execs), rec.execs)
+	}
+	if err := du.SaveMany(&s); err != ErrNoTx {
+		t.Errorf("expected ErrNoTx, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package dbobj
+
+import (
+	"database/sql"
+	"log"
+)
+
+// Page returns up to limit objects of type T matching the optional where clause,
+// starting at offset and ordered by key, along with the total number of matching
+// objects, e.g., for API list endpoints. Both queries run in one transaction
+// so the total is consistent with the page. A limit of zero returns all objects
+func Page[T DBObject](du *DBU, where string, limit, offset int, args ...interface{}) (items []T, total int64, err error) {
+	db := du.reader()
+	if db == nil {
+		return nil, 0, du.noTx()
+	}
+	var zero T
+	o := newObject(zero)
+	filter := ""
+	if where != "" {
+		filter = " where " + where
+	}
+	table := du.dialect.Quote(tableName(o))
+	count := "select count(*) from " + table + filter
+	query := "select " + du.dialect.selectFields(o) + " from " + table + filter
+	if key := o.KeyField(); len(key) > 0 {
+		query += " order by " + du.dialect.Quote(key)
+	}
+	pageArgs := args
+	if limit > 0 {
+		query += " limit " + du.dialect.Placeholder(len(args)+1) + " offset " + du.dialect.Placeholder(len(args)+2)
+		pageArgs = append(append([]interface{}{}, args...), limit, offset)
+	}
+
+	ctx, cancel := sqlWrapper{db, du.queryTimeout}.context()
+	defer cancel()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		if e := tx.Rollback(); e != nil && e != sql.ErrTxDone {
+			log.Printf("page rollback error: %v\n", e)
+		}
+	}()
+
+	du.debugf("Q: %s A:%v\n", count, args)
+	if err = tx.QueryRowContext(ctx, count, args...).Scan(&total); err != nil {
+		return nil, 0, queryError(err, o, count, args...)
+	}
+	du.debugf("Q: %s A:%v\n", query, pageArgs)
+	rows, err := tx.QueryContext(ctx, query, pageArgs...)
+	if err != nil {
+		return nil, 0, queryError(err, o, query, pageArgs...)
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, 0, err
+	}
+	for rows.Next() {
+		item := newObject(zero).(T)
+		dest := item.MemberPointers()
+		if err = columnCheck(query, len(cols), len(dest)); err != nil {
+			return nil, 0, err
+		}
+		if err = rows.Scan(dest...); err != nil {
+			return nil, 0, queryError(err, o, query, pageArgs...)
+		}
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return items, total, tx.Commit()
+}
@@ -0,0 +1,34 @@
+package dbobj
+
+import (
+	"testing"
+)
+
+func TestPage(t *testing.T) {
+	db := structDBU(t)
+	all := int64(countStructs(t, db))
+	items, total, err := Page[*testStruct](db, "", 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != all {
+		t.Errorf("expected total %d, got %d", all, total)
+	}
+	if len(items) != 2 || items[0].Name != "ghi" || items[1].Name != "jkl" {
+		t.Errorf("expected the second page of two, got %+v", items)
+	}
+	items, total, err = Page[*testStruct](db, "", 4, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != all || int64(len(items)) != all-4 {
+		t.Errorf("expected the last %d of %d, got %d of %d", all-4, all, len(items), total)
+	}
+	items, total, err = Page[*testStruct](db, "kind=?", 1, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 || len(items) != 1 || items[0].Name != "jkl" {
+		t.Errorf("expected the first of 3 matches, got %+v of %d", items, total)
+	}
+}
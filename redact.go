@@ -0,0 +1,60 @@
+package dbobj
+
+import (
+	"database/sql/driver"
+)
+
+// sensitiver is implemented by generated objects to list the columns,
+// e.g., password hashes or tokens, whose values must not be logged
+type sensitiver interface {
+	Sensitive() []string
+}
+
+// redacted is a query argument written as its value but logged as ***
+type redacted struct {
+	v interface{}
+}
+
+// Value satisfies the driver.Valuer interface
+func (r redacted) Value() (driver.Value, error) {
+	return driver.DefaultParameterConverter.ConvertValue(r.v)
+}
+
+// String satisfies the fmt.Stringer interface
+func (r redacted) String() string {
+	return "***"
+}
+
+// GoString satisfies the fmt.GoStringer interface
+func (r redacted) GoString() string {
+	return "***"
+}
+
+// redact returns the args with those bound to one of the object's sensitive
+// columns wrapped so they are logged as ***. The column of each arg is named
+// by its position in cols, and args beyond them, e.g., the key of an update
+// where it isn't listed, are left as is
+func redact(o DBObject, cols []string, args []interface{}) []interface{} {
+	s, ok := o.(sensitiver)
+	if !ok {
+		return args
+	}
+	secret := make(map[string]bool)
+	for _, col := range s.Sensitive() {
+		secret[col] = true
+	}
+	var list []interface{}
+	for i, arg := range args {
+		if i >= len(cols) || !secret[cols[i]] {
+			continue
+		}
+		if list == nil {
+			list = append([]interface{}{}, args...)
+		}
+		list[i] = redacted{arg}
+	}
+	if list == nil {
+		return args
+	}
+	return list
+}
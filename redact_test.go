@@ -0,0 +1,84 @@
+package dbobj
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// secretStruct stores a sensitive value in its data column
+type secretStruct struct {
+	testStruct
+}
+
+func (s *secretStruct) Sensitive() []string {
+	return []string{"data"}
+}
+
+func TestRedact(t *testing.T) {
+	db := structDBU(t)
+	logger := &captureLogger{}
+	db.SetLogger(logger)
+	s := &secretStruct{}
+	s.Name = "secret"
+	s.Data = "hunter2"
+	if err := db.Add(s); err != nil {
+		t.Fatal(err)
+	}
+	s.Data = "hunter3"
+	if err := db.Save(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveFields(s, "data"); err != nil {
+		t.Fatal(err)
+	}
+	var logged []string
+	for i, msg := range logger.msgs {
+		logged = append(logged, fmt.Sprintf(msg, logger.args[i]...))
+	}
+	all := strings.Join(logged, "")
+	if strings.Contains(all, "hunter") {
+		t.Errorf("expected sensitive values to be redacted:\n%s", all)
+	}
+	if strings.Count(all, "***") != 3 {
+		t.Errorf("expected 3 redacted args:\n%s", all)
+	}
+	if !strings.Contains(all, "secret") {
+		t.Errorf("expected other values to be logged:\n%s", all)
+	}
+
+	var data string
+	if err := db.ScanValue(&data, "select data from structs where id=?", s.ID); err != nil {
+		t.Fatal(err)
+	}
+	if data != "hunter3" {
+		t.Errorf("expected hunter3 to be written, got %q", data)
+	}
+}
+
+func TestRedactByColumn(t *testing.T) {
+	db := structDBU(t)
+	logger := &captureLogger{}
+	db.SetLogger(logger)
+	s := &secretStruct{}
+	s.Name = "same"
+	s.Data = "same"
+	if err := db.Add(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Save(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveFields(s, "name", "data"); err != nil {
+		t.Fatal(err)
+	}
+	for i, msg := range logger.msgs {
+		logged := fmt.Sprintf(msg, logger.args[i]...)
+		if strings.Count(logged, "same") != 1 || strings.Count(logged, "***") != 1 {
+			t.Errorf("expected only the data column to be redacted: %s", logged)
+		}
+	}
+	if len(logger.msgs) != 3 {
+		t.Errorf("expected 3 logged queries, got %d", len(logger.msgs))
+	}
+}
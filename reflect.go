@@ -0,0 +1,426 @@
+package dbobj
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The Object* methods provide a codegen-free alternative to DBObject,
+// using reflection over the struct tags of plain structs (or pointers to them)
+
+// structType returns the underlying struct type of obj
+func structType(obj interface{}) reflect.Type {
+	t := reflect.TypeOf(obj)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func keyIsSet(obj interface{}) bool {
+	val := reflect.Indirect(reflect.ValueOf(obj))
+	t := val.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Tag.Get("key") == "true" {
+			v := val.Field(i).Interface()
+			switch v.(type) {
+			case int:
+				return v.(int) > 0
+			case int64:
+				return v.(int64) > 0
+			default:
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// generate list of sql fields for members.
+// if skipKey is true, do not include the key field in the list
+func dbFields(obj interface{}, skipKey bool) (table, key, fields string) {
+	t := structType(obj)
+	list := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if isTable := f.Tag.Get("table"); len(isTable) > 0 {
+			table = isTable
+		}
+		k := f.Tag.Get("sql")
+		if f.Tag.Get("key") == "true" {
+			key = k
+			if skipKey {
+				continue
+			}
+		}
+		if len(k) > 0 {
+			list = append(list, k)
+		}
+	}
+	fields = strings.Join(list, ",")
+	return
+}
+
+// insertable reports whether the field is written on insert,
+// fields tagged insert:"false" are left to the column's default
+func insertable(f reflect.StructField) bool {
+	return len(f.Tag.Get("sql")) > 0 && f.Tag.Get("insert") != "false"
+}
+
+// updatable reports whether the object's column may be updated,
+// as columns of fields tagged update:"false" may not
+func updatable(obj interface{}, column string) bool {
+	t := reflect.Indirect(reflect.ValueOf(obj)).Type()
+	if t.Kind() != reflect.Struct {
+		return true
+	}
+	for _, f := range reflect.VisibleFields(t) {
+		if f.Tag.Get("sql") == column && f.Tag.Get("update") == "false" {
+			return false
+		}
+	}
+	return true
+}
+
+// objFields marshals the insertable object fields into an array,
+// returning their sql field names and values
+func objFields(obj interface{}, skipKey bool) ([]string, []interface{}) {
+	val := reflect.Indirect(reflect.ValueOf(obj))
+	t := val.Type()
+	fields := make([]string, 0, t.NumField())
+	a := make([]interface{}, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !insertable(f) {
+			continue
+		}
+		if skipKey && f.Tag.Get("key") == "true" {
+			continue
+		}
+		fields = append(fields, f.Tag.Get("sql"))
+		a = append(a, Converted(val.Field(i).Interface()))
+	}
+	return fields, a
+}
+
+// ObjectInsert inserts an object, returning the id of the new record.
+// Fields tagged insert:"false" are left out so the column's default applies
+func (du *DBU) ObjectInsert(obj interface{}) (int64, error) {
+	skip := !keyIsSet(obj) // if we have a key, we should probably use it
+	fields, a := objFields(obj, skip)
+	table, _, _ := dbFields(obj, skip)
+	if len(table) == 0 {
+		return -1, fmt.Errorf("no table defined for object: %v (fields: %s)", reflect.TypeOf(obj), strings.Join(fields, ","))
+	}
+	query := fmt.Sprintf("insert into %s (%s) values (%s)", du.dialect.Quote(table), strings.Join(du.dialect.quoteAll(fields), ","), du.dialect.Placeholders(1, len(a)))
+	du.debugf("Q: %s A: %v\n", query, a)
+	_, id, err := du.Exec(query, a...)
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+// ObjectUpdate updates an object
+func (du *DBU) ObjectUpdate(obj interface{}) error {
+	var table, key string
+	var id interface{}
+	val := reflect.Indirect(reflect.ValueOf(obj))
+	t := val.Type()
+	list := make([]string, 0, t.NumField())
+	args := make([]interface{}, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if isTable := f.Tag.Get("table"); len(isTable) > 0 {
+			table = isTable
+		}
+		if len(f.Tag.Get("sql")) == 0 {
+			continue
+		}
+		if f.Tag.Get("update") == "false" {
+			continue
+		}
+		k := f.Tag.Get("sql")
+		v := val.Field(i).Interface()
+		isKey := f.Tag.Get("key")
+		if isKey == "true" {
+			key = k
+			id = v
+			continue
+		}
+		args = append(args, Converted(v))
+		list = append(list, fmt.Sprintf("%s=%s", du.dialect.Quote(k), du.dialect.Placeholder(len(args))))
+	}
+	if len(key) == 0 {
+		return ErrNoKeyField
+	}
+	args = append(args, id)
+	query := fmt.Sprintf("update %s set %s where %s=%s", du.dialect.Quote(table), strings.Join(list, ","), du.dialect.Quote(key), du.dialect.Placeholder(len(args)))
+	du.debugf("Q: %s A: %v\n", query, args)
+	_, _, err := du.Exec(query, args...)
+	return err
+}
+
+func deleteInfo(obj interface{}) (table, key string, id interface{}) {
+	val := reflect.Indirect(reflect.ValueOf(obj))
+	t := val.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if isTable := f.Tag.Get("table"); len(isTable) > 0 {
+			table = isTable
+		}
+		if len(f.Tag.Get("sql")) == 0 {
+			continue
+		}
+		if f.Tag.Get("update") == "false" {
+			continue
+		}
+		k := f.Tag.Get("sql")
+		v := val.Field(i).Interface()
+		isKey := f.Tag.Get("key")
+		if isKey == "true" {
+			key = k
+			id = v
+			break
+		}
+	}
+	return
+}
+
+// ObjectDelete deletes the object
+func (du *DBU) ObjectDelete(obj interface{}) error {
+	table, key, id := deleteInfo(obj)
+	if len(key) == 0 {
+		return ErrNoKeyField
+	}
+	query := fmt.Sprintf("delete from %s where %s=%s", du.dialect.Quote(table), du.dialect.Quote(key), du.dialect.Placeholder(1))
+	du.debugf("Q: %s A: %v\n", query, id)
+	updated, _, err := du.Exec(query, id)
+	if err != nil {
+		return errors.Wrapf(err, "delete failed for query: %s id: %v", query, id)
+	}
+	if updated == 0 {
+		return errors.Wrapf(ErrNotFound, "no record deleted for id: %v", id)
+	}
+	return nil
+}
+
+// sPtrs makes slice of pointers to struct members for sql scanner
+// expects pointer to struct as input
+func sPtrs(obj interface{}) []interface{} {
+	base := reflect.Indirect(reflect.ValueOf(obj))
+	t := base.Type()
+	data := make([]interface{}, 0, base.NumField())
+	for i := 0; i < base.NumField(); i++ {
+		if tag := t.Field(i).Tag.Get("sql"); len(tag) > 0 {
+			data = append(data, ConvertedPtr(base.Field(i).Addr().Interface()))
+		}
+	}
+	return data
+}
+
+// ObjectLoad loads an object (which must be a pointer) with matching record info
+func (du *DBU) ObjectLoad(obj interface{}, extra string, args ...interface{}) error {
+	query, err := createQuery(du.dialect, obj, false)
+	if err != nil {
+		return err
+	}
+	if len(extra) > 0 {
+		query += " " + extra
+	}
+	return du.load(sPtrs(obj), query, args...)
+}
+
+// LoadMany returns a slice of kind populated by the query
+func (du *DBU) LoadMany(query string, kind interface{}, args ...interface{}) (interface{}, error) {
+	return du.queryObjects(kind, query, args...)
+}
+
+// ObjectListQuery returns a list of objects matching the where clause.
+// The orderBy columns must be sql fields of kind, optionally followed by asc or desc,
+// and if limit is greater than zero it is bound as the row limit
+func (du *DBU) ObjectListQuery(kind interface{}, where, orderBy string, limit int, args ...interface{}) (interface{}, error) {
+	query, err := createQuery(du.dialect, kind, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(where) > 0 {
+		query += " where " + where
+	}
+	if len(orderBy) > 0 {
+		order, err := orderClause(du.dialect, kind, orderBy)
+		if err != nil {
+			return nil, err
+		}
+		query += " order by " + order
+	}
+	if limit > 0 {
+		query += " limit " + du.dialect.Placeholder(len(args)+1)
+		args = append(args[:len(args):len(args)], limit)
+	}
+	return du.queryObjects(kind, query, args...)
+}
+
+// ObjectCount returns the number of records of kind's table matching the optional where clause,
+// complementing ObjectListQuery for pagination
+func (du *DBU) ObjectCount(kind interface{}, where string, args ...interface{}) (int64, error) {
+	table, _, _ := dbFields(kind, false)
+	if len(table) == 0 {
+		return 0, fmt.Errorf("no table name specified for object: %s", structType(kind).Name())
+	}
+	query := "select count(*) from " + du.dialect.Quote(table)
+	if len(where) > 0 {
+		query += " where " + where
+	}
+	var count int64
+	err := du.load([]interface{}{&count}, query, args...)
+	return count, err
+}
+
+// queryObjects returns a slice of the struct type of kind populated by the query
+func (du *DBU) queryObjects(kind interface{}, query string, args ...interface{}) (interface{}, error) {
+	t := structType(kind)
+	var rows []reflect.Value
+	fn := func() []interface{} {
+		v := reflect.New(t)
+		rows = append(rows, v)
+		return sPtrs(v.Interface())
+	}
+	du.debugf("Q: %s A: %v\n", query, args)
+	if err := du.Query(fn, query, args...); err != nil {
+		return nil, errors.Wrapf(err, "error on query: %s", query)
+	}
+	results := reflect.MakeSlice(reflect.SliceOf(t), 0, len(rows))
+	for _, v := range rows {
+		results = reflect.Append(results, v.Elem())
+	}
+	return results.Interface(), nil
+}
+
+// orderClause validates a comma separated list of columns against the sql fields of obj,
+// each optionally followed by asc or desc
+func orderClause(d Dialect, obj interface{}, orderBy string) (string, error) {
+	_, _, fields := dbFields(obj, false)
+	known := make(map[string]struct{})
+	for _, f := range strings.Split(fields, ",") {
+		known[f] = struct{}{}
+	}
+	terms := strings.Split(orderBy, ",")
+	for i, term := range terms {
+		words := strings.Fields(term)
+		if len(words) == 0 || len(words) > 2 {
+			return "", errors.Wrapf(ErrInvalidOrder, "order by: %q", term)
+		}
+		if _, ok := known[words[0]]; !ok {
+			return "", errors.Wrapf(ErrInvalidOrder, "order by: %q", words[0])
+		}
+		if len(words) == 2 {
+			dir := strings.ToLower(words[1])
+			if dir != "asc" && dir != "desc" {
+				return "", errors.Wrapf(ErrInvalidOrder, "order by: %q", term)
+			}
+			words[1] = dir
+		}
+		words[0] = d.Quote(words[0])
+		terms[i] = strings.Join(words, " ")
+	}
+	return strings.Join(terms, ","), nil
+}
+
+// createQuery returns the select statement for the sql fields of obj
+func createQuery(d Dialect, obj interface{}, skipKey bool) (string, error) {
+	var table string
+	t := structType(obj)
+	list := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if len(f.Tag.Get("sql")) == 0 {
+			continue
+		}
+		name := f.Tag.Get("table")
+		if len(name) > 0 {
+			table = name
+		}
+		if skipKey {
+			key := f.Tag.Get("key")
+			if key == "true" {
+				continue
+			}
+		}
+		list = append(list, f.Tag.Get("sql"))
+	}
+	if len(table) == 0 {
+		return "", fmt.Errorf("no table name specified for object: %s", t.Name())
+	}
+	return "select " + strings.Join(d.quoteAll(list), ",") + " from " + d.Quote(table), nil
+}
+
+// QueryStruct scans the first row of the query into dest, a pointer to a struct,
+// whose sql tagged fields must match the selected columns in order.
+// It returns ErrNotFound if there are no rows
+func (du *DBU) QueryStruct(dest interface{}, query string, args ...interface{}) error {
+	if t := reflect.TypeOf(dest); t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("dest must be a pointer to a struct, not %T", dest)
+	}
+	members := sPtrs(dest)
+	found := false
+	fn := func() []interface{} {
+		if found {
+			// scan subsequent rows into a throwaway
+			return sPtrs(reflect.New(structType(dest)).Interface())
+		}
+		found = true
+		return members
+	}
+	query, args = expandArgs(du.dialect, query, args)
+	du.debugf("Q: %s A: %v\n", query, args)
+	if err := du.Query(fn, query, args...); err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// QueryStructs appends the rows of the query to sliceDest, a pointer to a slice
+// of structs (or pointers to structs), whose sql tagged fields must match the
+// selected columns in order
+func (du *DBU) QueryStructs(sliceDest interface{}, query string, args ...interface{}) error {
+	t := reflect.TypeOf(sliceDest)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("sliceDest must be a pointer to a slice, not %T", sliceDest)
+	}
+	slice := reflect.ValueOf(sliceDest).Elem()
+	elem := t.Elem().Elem()
+	isPtr := elem.Kind() == reflect.Ptr
+	if isPtr {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return fmt.Errorf("sliceDest must be a slice of structs, not %T", sliceDest)
+	}
+	var rows []reflect.Value
+	fn := func() []interface{} {
+		v := reflect.New(elem)
+		rows = append(rows, v)
+		return sPtrs(v.Interface())
+	}
+	query, args = expandArgs(du.dialect, query, args)
+	du.debugf("Q: %s A: %v\n", query, args)
+	if err := du.Query(fn, query, args...); err != nil {
+		return errors.Wrapf(err, "error on query: %s", query)
+	}
+	for _, v := range rows {
+		if !isPtr {
+			v = v.Elem()
+		}
+		slice = reflect.Append(slice, v)
+	}
+	reflect.ValueOf(sliceDest).Elem().Set(slice)
+	return nil
+}
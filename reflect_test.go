@@ -0,0 +1,258 @@
+package dbobj
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestObjects(t *testing.T) {
+	db := structDBU(t)
+	s1 := testStruct{
+		Name: "Bobby Tables",
+		Kind: 23,
+		Data: "binary data",
+	}
+	var err error
+	s1.ID, err = db.ObjectInsert(s1)
+	if err != nil {
+		t.Errorf("OBJ INSERT ERROR: %s", err)
+	}
+	s2 := testStruct{
+		Name: "Master Blaster",
+		Kind: 999,
+		Data: "whatever you like",
+	}
+	s2.ID, err = db.ObjectInsert(&s2)
+	if err != nil {
+		t.Errorf("OBJ INSERT ERROR: %s", err)
+	}
+	s1.Kind = 99
+	if err = db.ObjectUpdate(s1); err != nil {
+		t.Errorf("OBJ UPDATE ERROR: %s", err)
+	}
+	s2.Name = "New Name"
+	if err = db.ObjectUpdate(&s2); err != nil {
+		t.Errorf("OBJ UPDATE ERROR: %s", err)
+	}
+	u := testStruct{}
+	if err = db.ObjectLoad(&u, "where id=?", s1.ID); err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != "Bobby Tables" || u.Kind != 99 {
+		t.Errorf("unexpected record: %+v", u)
+	}
+	if err = db.ObjectDelete(s2); err != nil {
+		t.Errorf("OBJ DELETE ERROR: %s", err)
+	}
+	if err = db.ObjectDelete(s2); errors.Cause(err) != ErrNotFound {
+		t.Errorf("expected ErrNotFound deleting twice, got %v", err)
+	}
+}
+
+func TestObjectInsert(t *testing.T) {
+	db := structDBU(t)
+	s := testStruct{
+		Name: "Blur",
+		Kind: 13,
+	}
+	i, err := db.ObjectInsert(s)
+	if err != nil {
+		t.Error(err)
+	}
+	if !(i > 0) {
+		t.Errorf("expected last row to be greater than zero: %d", i)
+	}
+}
+
+func TestLoadMany(t *testing.T) {
+	db := structDBU(t)
+	list, err := db.LoadMany("select id,name,kind,data,modified from structs where kind=?", testStruct{}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items := list.([]testStruct); len(items) != 3 {
+		t.Errorf("expected 3 items, got %d", len(items))
+	}
+}
+
+func TestObjectListQueryOrder(t *testing.T) {
+	db := structDBU(t)
+	list, err := db.ObjectListQuery(testStruct{}, "", "kind desc", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := list.([]testStruct)
+	if len(items) != 6 {
+		t.Fatalf("expected 6 items, got %d", len(items))
+	}
+	for i := 1; i < len(items); i++ {
+		if items[i].Kind > items[i-1].Kind {
+			t.Errorf("items not in descending order: %d before %d", items[i-1].Kind, items[i].Kind)
+		}
+	}
+}
+
+func TestObjectListQueryLimit(t *testing.T) {
+	db := structDBU(t)
+	list, err := db.ObjectListQuery(testStruct{}, "kind > ?", "name", 2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := list.([]testStruct)
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(items))
+	}
+	if items[0].Name != "abc" || items[1].Name != "def" {
+		t.Errorf("unexpected items: %+v", items)
+	}
+}
+
+func TestObjectCount(t *testing.T) {
+	db := structDBU(t)
+	all, err := db.ObjectCount(testStruct{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := db.ObjectListQuery(testStruct{}, "", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int(all) != len(list.([]testStruct)) {
+		t.Errorf("expected %d rows, got %d", len(list.([]testStruct)), all)
+	}
+	some, err := db.ObjectCount(&testStruct{}, "kind > ?", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if some == 0 || some >= all {
+		t.Errorf("expected filtered count between 0 and %d, got %d", all, some)
+	}
+	type untabled struct {
+		ID int64 `sql:"id"`
+	}
+	if _, err := db.ObjectCount(untabled{}, ""); err == nil {
+		t.Error("expected error for object without a table")
+	}
+}
+
+func TestObjectListQueryBadOrder(t *testing.T) {
+	db := structDBU(t)
+	_, err := db.ObjectListQuery(testStruct{}, "", "name; drop table structs", 0)
+	if errors.Cause(err) != ErrInvalidOrder {
+		t.Errorf("expected ErrInvalidOrder, got %v", err)
+	}
+}
+
+// stampedStruct leaves its timestamp to the column default
+type stampedStruct struct {
+	ID       int64     `sql:"id" key:"true" table:"structs"`
+	Name     string    `sql:"name"`
+	Modified time.Time `sql:"modified" insert:"false"`
+}
+
+func TestObjectInsertDefault(t *testing.T) {
+	db := structDBU(t)
+	before := time.Now().UTC().Add(-time.Minute)
+	s := stampedStruct{Name: "stamped", Modified: time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC)}
+	id, err := db.ObjectInsert(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := stampedStruct{}
+	if err := db.ObjectLoad(&u, "where id=?", id); err != nil {
+		t.Fatal(err)
+	}
+	if u.Modified.Before(before) {
+		t.Errorf("expected the default timestamp, got %v", u.Modified)
+	}
+}
+
+// kindReport is a report-shaped struct that doesn't implement DBObject
+type kindReport struct {
+	Kind  int    `sql:"kind"`
+	Count int    `sql:"count"`
+	First string `sql:"first"`
+}
+
+func TestQueryStructs(t *testing.T) {
+	db := structDBU(t)
+	const query = "select kind, count(*), min(name) from structs group by kind order by kind"
+	var reports []kindReport
+	if err := db.QueryStructs(&reports, query); err != nil {
+		t.Fatal(err)
+	}
+	want := []kindReport{{2, 3, "jkl"}, {23, 1, "abc"}, {42, 1, "ghi"}, {69, 1, "def"}}
+	if !reflect.DeepEqual(reports, want) {
+		t.Errorf("expected %v, got %v", want, reports)
+	}
+	var ptrs []*kindReport
+	if err := db.QueryStructs(&ptrs, query+" limit 1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(ptrs) != 1 || *ptrs[0] != want[0] {
+		t.Errorf("expected %v, got %v", want[:1], ptrs)
+	}
+	if err := db.QueryStructs(reports, query); err == nil {
+		t.Error("expected error for non-pointer destination")
+	}
+}
+
+func TestQueryStruct(t *testing.T) {
+	db := structDBU(t)
+	const query = "select kind, count(*), min(name) from structs where kind=? group by kind"
+	var report kindReport
+	if err := db.QueryStruct(&report, query, 2); err != nil {
+		t.Fatal(err)
+	}
+	if report != (kindReport{2, 3, "jkl"}) {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if err := db.QueryStruct(&report, query, 999); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+// reservedStruct has a table and columns named for reserved words
+type reservedStruct struct {
+	ID    int64  `sql:"key" key:"true" table:"order"`
+	Group string `sql:"group"`
+}
+
+func TestObjectReserved(t *testing.T) {
+	db := structDBU(t)
+	if _, _, err := db.Exec(`create table "order" ("key" integer primary key, "group" text)`); err != nil {
+		t.Fatal(err)
+	}
+	o := reservedStruct{Group: "first"}
+	var err error
+	if o.ID, err = db.ObjectInsert(o); err != nil {
+		t.Fatal(err)
+	}
+	o.Group = "second"
+	if err := db.ObjectUpdate(o); err != nil {
+		t.Fatal(err)
+	}
+	got := reservedStruct{}
+	if err := db.ObjectLoad(&got, `where "key"=?`, o.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got.Group != "second" {
+		t.Errorf("expected group second, got %+v", got)
+	}
+	list, err := db.ObjectListQuery(reservedStruct{}, "", "group desc", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items := list.([]reservedStruct); len(items) != 1 || items[0] != got {
+		t.Errorf("expected %+v, got %+v", got, items)
+	}
+	if count, err := db.ObjectCount(reservedStruct{}, ""); err != nil || count != 1 {
+		t.Errorf("expected 1 row, got %d (%v)", count, err)
+	}
+	if err := db.ObjectDelete(o); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,41 @@
+package dbobj
+
+import (
+	"sync"
+
+	"github.com/paulstuart/sqlite"
+	"github.com/pkg/errors"
+)
+
+// ErrUnknownOpener is returned when no opener is registered for the name
+var ErrUnknownOpener = errors.New("unknown opener")
+
+var (
+	openersMu sync.RWMutex
+	openers   = map[string]SQLDB{
+		"sqlite3": sqlite.Open,
+	}
+)
+
+// RegisterOpener makes an opener available by name to NewDBUNamed,
+// replacing any opener previously registered with the name
+func RegisterOpener(name string, opener SQLDB) {
+	openersMu.Lock()
+	defer openersMu.Unlock()
+	if opener == nil {
+		delete(openers, name)
+		return
+	}
+	openers[name] = opener
+}
+
+// NewDBUNamed returns a new DBU for file, opened by the opener registered as name
+func NewDBUNamed(name, file string) (*DBU, error) {
+	openersMu.RLock()
+	opener, ok := openers[name]
+	openersMu.RUnlock()
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownOpener, "name: %q", name)
+	}
+	return NewDBU(file, true, opener)
+}
@@ -0,0 +1,44 @@
+package dbobj
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestNewDBUNamed(t *testing.T) {
+	var opened string
+	RegisterOpener("fake", func(file string) (*sql.DB, error) {
+		opened = file
+		return sql.Open("noid", file)
+	})
+	defer RegisterOpener("fake", nil)
+	db, err := NewDBUNamed("fake", "fake.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.DB().Close()
+	if opened != "fake.db" {
+		t.Errorf("expected fake opener to open fake.db, got %q", opened)
+	}
+	if err := db.Add(&testStruct{Name: "fake"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewDBUNamed("bogus", "fake.db"); errors.Cause(err) != ErrUnknownOpener {
+		t.Errorf("expected ErrUnknownOpener, got %v", err)
+	}
+}
+
+func TestNewDBUNamedSQLite(t *testing.T) {
+	db, err := NewDBUNamed("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.Ping(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
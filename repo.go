@@ -0,0 +1,81 @@
+package dbobj
+
+// newer is implemented by generated objects to allocate a new instance
+type newer interface {
+	NewObj() interface{}
+}
+
+// Repo is a type-safe CRUD facade over a DBU for objects of type T,
+// where *T implements DBObject
+type Repo[T any, P interface {
+	*T
+	DBObject
+}] struct {
+	db *DBU
+}
+
+// NewRepo returns a Repo for objects of type T, e.g., NewRepo[User](db)
+func NewRepo[T any, P interface {
+	*T
+	DBObject
+}](db *DBU) *Repo[T, P] {
+	return &Repo[T, P]{db: db}
+}
+
+// alloc returns a new object, using its NewObj method if present
+func (r *Repo[T, P]) alloc() *T {
+	var p P
+	if n, ok := interface{}(p).(newer); ok {
+		if o, ok := n.NewObj().(*T); ok {
+			return o
+		}
+	}
+	return new(T)
+}
+
+// Get returns the object with the given id
+func (r *Repo[T, P]) Get(id interface{}) (*T, error) {
+	o := r.alloc()
+	if err := r.db.FindByID(P(o), id); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Create adds the object to the datastore
+func (r *Repo[T, P]) Create(o *T) error {
+	return r.db.Add(P(o))
+}
+
+// Update saves the object to the datastore
+func (r *Repo[T, P]) Update(o *T) error {
+	return r.db.Save(P(o))
+}
+
+// Delete removes the object from the datastore
+func (r *Repo[T, P]) Delete(o *T) error {
+	return r.db.Delete(P(o))
+}
+
+// List returns the objects matching the optional where clause
+func (r *Repo[T, P]) List(where string, args ...interface{}) ([]T, error) {
+	o := P(r.alloc())
+	query := "select " + r.db.dialect.selectFields(o) + " from " + r.db.dialect.Quote(tableName(o))
+	if where != "" {
+		query += " where " + where
+	}
+	var rows []*T
+	fn := func() []interface{} {
+		row := r.alloc()
+		rows = append(rows, row)
+		return P(row).MemberPointers()
+	}
+	if err := r.db.Query(fn, query, args...); err != nil {
+		return nil, err
+	}
+	list := make([]T, len(rows))
+	for i, row := range rows {
+		list[i] = *row
+	}
+	return list, nil
+}
@@ -0,0 +1,45 @@
+package dbobj
+
+import (
+	"testing"
+)
+
+func TestRepo(t *testing.T) {
+	db := structDBU(t)
+	repo := NewRepo[testStruct](db)
+	s := &testStruct{Name: "repo", Kind: 31, Data: "lifecycle"}
+	if err := repo.Create(s); err != nil {
+		t.Fatal(err)
+	}
+	got, err := repo.Get(s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "repo" || got.Kind != 31 {
+		t.Errorf("unexpected object: %+v", got)
+	}
+	got.Kind = 32
+	if err := repo.Update(got); err != nil {
+		t.Fatal(err)
+	}
+	list, err := repo.List("kind=?", 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != s.ID {
+		t.Errorf("expected updated object, got %+v", list)
+	}
+	all, err := repo.List("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 7 {
+		t.Errorf("expected 7 objects, got %d", len(all))
+	}
+	if err := repo.Delete(got); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Get(s.ID); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+}
@@ -3,30 +3,44 @@
 package dbobj
 
 import (
+	"context"
+	"database/sql/driver"
 	"fmt"
+	"regexp"
 	"strings"
+	"time"
 
 	rqlite "github.com/rqlite/gorqlite"
 )
 
+var singleQuote = regexp.MustCompile("'")
+
 type rqliteWrapper struct {
 	conn *rqlite.Connection
 }
 
+// Query satisfies DBS interface
 func (s rqliteWrapper) Query(fn SetHandler, query string, args ...interface{}) error {
-	// TODO: include args!
 	// TODO: build query buffer to batch
-	queries := []string{query}
+	rendered, err := renderQuery(query, args...)
+	if err != nil {
+		return err
+	}
+	queries := []string{rendered}
 	results, err := s.conn.Query(queries)
 	if err != nil {
 		return err
 	}
 	for _, result := range results {
+		cols := result.Columns()
 		for result.Next() {
 			dest := fn()
 			if dest == nil {
 				return ErrNilWritePointers
 			}
+			if err = columnCheck(query, len(cols), len(dest)); err != nil {
+				return err
+			}
 			if err = result.Scan(dest...); err != nil {
 				return err
 			}
@@ -35,8 +49,42 @@ func (s rqliteWrapper) Query(fn SetHandler, query string, args ...interface{}) e
 	return nil
 }
 
+// Exec satisfies DBS interface
 func (s rqliteWrapper) Exec(query string, args ...interface{}) (rowsAffected, lastInsertID int64, err error) {
-	return 0, 0, nil
+	rendered, err := renderQuery(query, args...)
+	if err != nil {
+		return 0, 0, err
+	}
+	results, err := s.conn.Write([]string{rendered})
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			return 0, 0, result.Err
+		}
+		rowsAffected += result.RowsAffected
+		lastInsertID = result.LastInsertID
+	}
+	return rowsAffected, lastInsertID, nil
+}
+
+// Ping verifies the rqlite node is responding
+func (s rqliteWrapper) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var one int64
+	fn := func() []interface{} {
+		return []interface{}{&one}
+	}
+	return s.Query(fn, "select 1")
+}
+
+// Close releases the connection to the rqlite node
+func (s rqliteWrapper) Close() error {
+	s.conn.Close()
+	return nil
 }
 
 func NewRqlite(addr string) (*rqliteWrapper, error) {
@@ -44,6 +92,44 @@ func NewRqlite(addr string) (*rqliteWrapper, error) {
 	return &rqliteWrapper{&r}, err
 }
 
+// NewRqliteDBU returns a DBU using the rqlite node at addr
+func NewRqliteDBU(addr string) (*DBU, error) {
+	r, err := NewRqlite(addr)
+	if err != nil {
+		return nil, err
+	}
+	return &DBU{dbs: r}, nil
+}
+
+// renderQuery substitutes the rendered args for the query's ? placeholders,
+// as rqlite doesn't support bind parameters
+func renderQuery(query string, args ...interface{}) (string, error) {
+	if len(args) == 0 {
+		return query, nil
+	}
+	var buf strings.Builder
+	quoted := false
+	next := 0
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == '?' && !quoted:
+			if next >= len(args) {
+				return "", fmt.Errorf("not enough args for query: %s", query)
+			}
+			buf.WriteString(renderedFields(args[next]))
+			next++
+			continue
+		}
+		buf.WriteRune(r)
+	}
+	if next != len(args) {
+		return "", fmt.Errorf("too many args (%d) for query: %s", len(args), query)
+	}
+	return buf.String(), nil
+}
+
 // renderedFields is because rqlite doesn't support bind parameters
 func renderedFields(values ...interface{}) string {
 	var buf strings.Builder
@@ -51,7 +137,22 @@ func renderedFields(values ...interface{}) string {
 		if i > 0 {
 			buf.WriteString(", ")
 		}
+		if valuer, ok := value.(driver.Valuer); ok {
+			if v, err := valuer.Value(); err == nil {
+				value = v
+			}
+		}
 		switch value := value.(type) {
+		case nil:
+			buf.WriteString("NULL")
+		case []byte:
+			buf.WriteString("'")
+			buf.WriteString(singleQuote.ReplaceAllString(string(value), "''"))
+			buf.WriteString("'")
+		case time.Time:
+			buf.WriteString("'")
+			buf.WriteString(value.Format("2006-01-02 15:04:05"))
+			buf.WriteString("'")
 		case string:
 			value = singleQuote.ReplaceAllString(value, "''")
 			buf.WriteString("'")
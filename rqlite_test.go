@@ -41,13 +41,13 @@ func prepareRqlite(conn *rqlite.Connection) {
 	*/
 }
 
-func structRqlite(t *testing.T) DBU {
+func structRqlite(t *testing.T) *DBU {
 	dbs, err := NewRqlite("http://localhost:4001")
 	if err != nil {
 		t.Fatal(err)
 	}
 	prepareRqlite(dbs.conn)
-	return DBU{dbs: dbs}
+	return &DBU{dbs: dbs}
 }
 
 func TestRqliteQuery(t *testing.T) {
@@ -58,3 +58,58 @@ func TestRqliteQuery(t *testing.T) {
 		t.Logf("ITEM:  %+v\n", item)
 	}
 }
+
+func TestRqliteFindByID(t *testing.T) {
+	db := structRqlite(t)
+	s := testStruct{}
+	if err := db.FindByID(&s, 2); err != nil {
+		t.Fatal(err)
+	}
+	if s.ID != 2 {
+		t.Errorf("expected id 2, got %+v", s)
+	}
+	u := testStruct{}
+	if err := db.FindBy(&u, "name", "def"); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("BY NAME: %+v", u)
+}
+
+func TestRenderQuery(t *testing.T) {
+	query, err := renderQuery("select * from structs where name=? and kind=? and data='?'", "m'kay", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "select * from structs where name='m''kay' and kind=2 and data='?'"
+	if query != want {
+		t.Errorf("expected %q, got %q", want, query)
+	}
+	if _, err := renderQuery("select * from structs where id=?"); err != nil {
+		t.Errorf("expected query without args to pass through, got %v", err)
+	}
+	if _, err := renderQuery("select * from structs where id=?", 1, 2); err == nil {
+		t.Error("expected error for too many args")
+	}
+}
+
+func TestRqliteAdd(t *testing.T) {
+	db := structRqlite(t)
+	s := testStruct{
+		Name: "rqlite",
+		Kind: 7,
+		Data: "through the DBS",
+	}
+	if err := db.Add(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.ID == 0 {
+		t.Fatal("expected id to be set")
+	}
+	u := testStruct{}
+	if err := db.FindByID(&u, s.ID); err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != s.Name || u.Kind != s.Kind {
+		t.Errorf("expected %+v, got %+v", s, u)
+	}
+}
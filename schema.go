@@ -0,0 +1,55 @@
+package dbobj
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// VerifySchema compares the object's columns to those of its table in the database,
+// returning a description of each column missing from either. It requires SQLite
+func (du *DBU) VerifySchema(o DBObject) ([]string, error) {
+	if du.dialect != SQLite {
+		return nil, errors.Errorf("schema verification requires sqlite, not %s", du.dialect)
+	}
+	table := tableName(o)
+	var names []*string
+	fn := func() []interface{} {
+		var cid, notNull, pk int64
+		var name, kind string
+		var dflt sql.NullString
+		names = append(names, &name)
+		return []interface{}{&cid, &name, &kind, &notNull, &dflt, &pk}
+	}
+	query := fmt.Sprintf("pragma table_info(%s)", SQLite.Quote(table))
+	du.debugf("Q: %s\n", query)
+	if err := du.Query(fn, query); err != nil {
+		return nil, queryError(err, o, query)
+	}
+	columns := make([]string, len(names))
+	for i, name := range names {
+		columns[i] = *name
+	}
+	if len(columns) == 0 {
+		return nil, errors.Wrapf(ErrNotFound, "table: %s", table)
+	}
+	inTable := make(map[string]bool, len(columns))
+	for _, col := range columns {
+		inTable[col] = true
+	}
+	var mismatches []string
+	inStruct := make(map[string]bool)
+	for _, col := range selectColumns(o) {
+		inStruct[col] = true
+		if !inTable[col] {
+			mismatches = append(mismatches, fmt.Sprintf("column %q of %T is missing from table %s", col, o, table))
+		}
+	}
+	for _, col := range columns {
+		if !inStruct[col] {
+			mismatches = append(mismatches, fmt.Sprintf("column %q of table %s is missing from %T", col, table, o))
+		}
+	}
+	return mismatches, nil
+}
@@ -0,0 +1,49 @@
+package dbobj
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// driftStruct declares a column the structs table doesn't have, and lacks modified
+type driftStruct struct {
+	testStruct
+}
+
+func (s *driftStruct) SelectFields() string {
+	return "id,name,kind,data,extra"
+}
+
+// missingStruct is stored in a table that doesn't exist
+type missingStruct struct {
+	testStruct
+}
+
+func (s *missingStruct) TableName() string {
+	return "missing"
+}
+
+func TestVerifySchema(t *testing.T) {
+	db := structDBU(t)
+	mismatches, err := db.VerifySchema(&testStruct{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) > 0 {
+		t.Errorf("expected no mismatches, got %v", mismatches)
+	}
+
+	mismatches, err = db.VerifySchema(&driftStruct{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 2 || !strings.Contains(mismatches[0], `"extra"`) || !strings.Contains(mismatches[1], `"modified"`) {
+		t.Errorf("expected extra and modified mismatches, got %v", mismatches)
+	}
+
+	if _, err := db.VerifySchema(&missingStruct{}); errors.Cause(err) != ErrNotFound {
+		t.Errorf("expected ErrNotFound for a missing table, got %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package dbobj
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"log"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ExecFile executes the statements of the sql file in a single transaction
+func (du *DBU) ExecFile(path string) error {
+	script, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return errors.Wrapf(du.ExecScript(string(script)), "file: %s", path)
+}
+
+// ExecScript executes the semicolon separated statements of the script
+// in a single transaction, e.g., for schema setup and seeding
+func (du *DBU) ExecScript(script string) error {
+	if du.readOnly {
+		return ErrReadOnly
+	}
+	statements := splitStatements(script)
+	if du.dryRun {
+		for _, query := range statements {
+			du.debugf("DRY RUN Q: %s\n", query)
+		}
+		return nil
+	}
+	if du.db == nil {
+		return du.noTx()
+	}
+	ctx, cancel := sqlWrapper{du.db, du.queryTimeout}.context()
+	defer cancel()
+	tx, err := du.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, query := range statements {
+		du.debugf("Q: %s\n", query)
+		if _, err = tx.ExecContext(ctx, query); err != nil {
+			if e := tx.Rollback(); e != nil && e != sql.ErrTxDone {
+				log.Printf("exec rollback error: %v\n", e)
+			}
+			return errors.Wrapf(err, "statement: %s", query)
+		}
+	}
+	return tx.Commit()
+}
+
+// splitStatements splits the script on semicolons outside of quotes,
+// dropping comments and empty statements
+func splitStatements(script string) []string {
+	var statements []string
+	var b strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(b.String()); s != "" {
+			statements = append(statements, s)
+		}
+		b.Reset()
+	}
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := quotedEnd(script, i)
+			b.WriteString(script[i : end+1])
+			i = end
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += end - 1
+			}
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += end + 3
+			}
+			b.WriteByte(' ')
+		case c == ';':
+			flush()
+		default:
+			b.WriteByte(c)
+		}
+	}
+	flush()
+	return statements
+}
+
+// quotedEnd returns the index of the quote closing the text quoted at i,
+// where a doubled quote is an escaped quote, or the last index if it's unterminated
+func quotedEnd(s string, i int) int {
+	c := s[i]
+	end := i + 1
+	for end < len(s) {
+		if s[end] == c {
+			if end+1 < len(s) && s[end+1] == c {
+				end += 2
+				continue
+			}
+			return end
+		}
+		end++
+	}
+	return len(s) - 1
+}
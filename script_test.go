@@ -0,0 +1,84 @@
+package dbobj
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testScript = `
+-- seed the colors; a comment with a ; in it
+create table colors (
+	id integer primary key,
+	name text, /* the display name; required */
+	hex text
+);
+insert into colors (name, hex) values ('red', '#f00');
+insert into colors (name, hex) values ('semi;colon', '#0f0');
+insert into colors (name, hex) values ('it''s blue', "#00f");
+`
+
+func TestSplitStatements(t *testing.T) {
+	got := splitStatements(testScript)
+	if len(got) != 4 {
+		t.Fatalf("expected 4 statements, got %d: %q", len(got), got)
+	}
+	want := []string{
+		"insert into colors (name, hex) values ('red', '#f00')",
+		"insert into colors (name, hex) values ('semi;colon', '#0f0')",
+		`insert into colors (name, hex) values ('it''s blue', "#00f")`,
+	}
+	if !reflect.DeepEqual(got[1:], want) {
+		t.Errorf("expected %q, got %q", want, got[1:])
+	}
+	if got := splitStatements("select 1;\n; -- trailing\n"); len(got) != 1 || got[0] != "select 1" {
+		t.Errorf("expected a single statement, got %q", got)
+	}
+}
+
+func TestExecFile(t *testing.T) {
+	db := structDBU(t)
+	dir, err := ioutil.TempDir("", "dbobj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "seed.sql")
+	if err := ioutil.WriteFile(path, []byte(testScript), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.ExecFile(path); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	var name string
+	fn := func() []interface{} {
+		if name != "" {
+			names = append(names, name)
+		}
+		return []interface{}{&name}
+	}
+	if err := db.Query(fn, "select name from colors order by id"); err != nil {
+		t.Fatal(err)
+	}
+	names = append(names, name)
+	want := []string{"red", "semi;colon", "it's blue"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("expected %q, got %q", want, names)
+	}
+	if err := db.ExecScript("insert into colors (name) values ('rolled back'); insert into nowhere values (1);"); err == nil {
+		t.Fatal("expected error for missing table")
+	}
+	var count int64
+	if err := db.ScanValue(&count, "select count(*) from colors"); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("expected the failed script to roll back, got %d rows", count)
+	}
+	if err := db.ExecFile(filepath.Join(dir, "missing.sql")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
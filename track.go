@@ -0,0 +1,72 @@
+package dbobj
+
+import (
+	"database/sql/driver"
+	"reflect"
+)
+
+// Tracked is a snapshot of an object's column values,
+// used to save only the columns changed since
+type Tracked struct {
+	o        DBObject
+	snapshot map[string]interface{}
+}
+
+// Track captures the current column values of the object
+func (du *DBU) Track(o DBObject) Tracked {
+	t := Tracked{o: o, snapshot: make(map[string]interface{})}
+	t.capture()
+	return t
+}
+
+// capture records the current column values
+func (t Tracked) capture() {
+	for col, ptr := range t.o.FieldMap() {
+		t.snapshot[col] = columnValue(ptr)
+	}
+}
+
+// Changed returns the columns whose values differ from the snapshot
+func (t Tracked) Changed() []string {
+	fields := t.o.FieldMap()
+	var changed []string
+	for _, col := range selectColumns(t.o) {
+		ptr, ok := fields[col]
+		if !ok || col == t.o.KeyField() {
+			continue
+		}
+		if !sameValue(columnValue(ptr), t.snapshot[col]) {
+			changed = append(changed, col)
+		}
+	}
+	return changed
+}
+
+// Save updates only the columns changed since the snapshot,
+// which is then refreshed
+func (t Tracked) Save(du *DBU) error {
+	changed := t.Changed()
+	if len(changed) == 0 {
+		return nil
+	}
+	if err := du.SaveFields(t.o, changed...); err != nil {
+		return err
+	}
+	t.capture()
+	return nil
+}
+
+// columnValue returns a copy of the value a member pointer refers to,
+// as the driver would see it for converters
+func columnValue(ptr interface{}) interface{} {
+	v := reflect.ValueOf(ptr).Elem().Interface()
+	if valuer, ok := v.(driver.Valuer); ok {
+		if dv, err := valuer.Value(); err == nil {
+			v = dv
+		}
+	}
+	if b, ok := v.([]byte); ok {
+		return append([]byte(nil), b...)
+	}
+	return v
+}
@@ -0,0 +1,43 @@
+package dbobj
+
+import (
+	"testing"
+)
+
+func TestTrack(t *testing.T) {
+	db := structDBU(t)
+	s := &testStruct{}
+	if err := db.FindByID(s, 3); err != nil {
+		t.Fatal(err)
+	}
+	counter := &queryCounter{}
+	db.SetLogger(counter)
+	tracked := db.Track(s)
+	if err := tracked.Save(db); err != nil {
+		t.Fatal(err)
+	}
+	if len(counter.queries) != 0 {
+		t.Fatalf("expected no update without changes, got %v", counter.queries)
+	}
+	s.Kind = 314
+	if changed := tracked.Changed(); len(changed) != 1 || changed[0] != "kind" {
+		t.Fatalf("expected only kind to change, got %v", changed)
+	}
+	if err := tracked.Save(db); err != nil {
+		t.Fatal(err)
+	}
+	const want = "update structs set kind=? where id=?"
+	if len(counter.queries) != 1 || counter.queries[0] != want {
+		t.Errorf("expected %q, got %v", want, counter.queries)
+	}
+	if changed := tracked.Changed(); len(changed) != 0 {
+		t.Errorf("expected snapshot to be refreshed, got %v", changed)
+	}
+	u := &testStruct{}
+	if err := db.FindByID(u, 3); err != nil {
+		t.Fatal(err)
+	}
+	if u.Kind != 314 || u.Name != s.Name {
+		t.Errorf("unexpected saved object: %+v", u)
+	}
+}
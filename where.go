@@ -0,0 +1,249 @@
+package dbobj
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidColumn is returned when a column name is not a valid identifier
+var ErrInvalidColumn = errors.New("invalid column name")
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Where composes predicates, joined by "and", into a where clause with bound args
+type Where struct {
+	terms   []term
+	args    []interface{}
+	columns []string
+	check   func(column string) error
+	err     error
+}
+
+// term renders a predicate of n args, numbering its placeholders from start
+type term struct {
+	render func(d Dialect, start int) string
+	n      int
+}
+
+// NewWhere returns an empty Where builder
+func NewWhere() *Where {
+	return &Where{}
+}
+
+// Check sets a hook to validate each column name as it is added
+func (w *Where) Check(fn func(column string) error) *Where {
+	w.check = fn
+	return w
+}
+
+// Eq adds a column=value predicate
+func (w *Where) Eq(column string, value interface{}) *Where {
+	return w.add(column, func(d Dialect, start int) string {
+		return d.Quote(column) + "=" + d.Placeholder(start)
+	}, value)
+}
+
+// In adds a column in (values...) predicate.
+// A single slice argument is expanded into its elements.
+// An empty list of values matches nothing
+func (w *Where) In(column string, values ...interface{}) *Where {
+	if len(values) == 1 {
+		if list, ok := sliceArg(values[0]); ok {
+			values = list
+		}
+	}
+	if len(values) == 0 {
+		return w.add(column, func(Dialect, int) string { return "1=0" })
+	}
+	return w.add(column, func(d Dialect, start int) string {
+		return fmt.Sprintf("%s in (%s)", d.Quote(column), d.Placeholders(start, len(values)))
+	}, values...)
+}
+
+// Like adds a column like pattern predicate
+func (w *Where) Like(column, pattern string) *Where {
+	return w.add(column, func(d Dialect, start int) string {
+		return d.Quote(column) + " like " + d.Placeholder(start)
+	}, pattern)
+}
+
+func (w *Where) add(column string, render func(d Dialect, start int) string, args ...interface{}) *Where {
+	if w.err != nil {
+		return w
+	}
+	if !identifier.MatchString(column) {
+		w.err = errors.Wrapf(ErrInvalidColumn, "column: %q", column)
+		return w
+	}
+	if w.check != nil {
+		if err := w.check(column); err != nil {
+			w.err = err
+			return w
+		}
+	}
+	w.terms = append(w.terms, term{render: render, n: len(args)})
+	w.args = append(w.args, args...)
+	w.columns = append(w.columns, column)
+	return w
+}
+
+// Clause returns the where clause (without the "where") and its args.
+// An empty builder returns an empty clause
+func (w *Where) Clause() (string, []interface{}) {
+	return w.DialectClause(SQLite, 1)
+}
+
+// DialectClause returns the where clause with the placeholders of the dialect,
+// numbered from start, e.g., to follow the args of the rest of the statement
+func (w *Where) DialectClause(d Dialect, start int) (string, []interface{}) {
+	if w == nil {
+		return "", nil
+	}
+	list := make([]string, len(w.terms))
+	for i, t := range w.terms {
+		list[i] = t.render(d, start)
+		start += t.n
+	}
+	return strings.Join(list, " and "), w.args
+}
+
+// Err returns the first error encountered while building the clause
+func (w *Where) Err() error {
+	if w == nil {
+		return nil
+	}
+	return w.err
+}
+
+// FindCond loads an object matching the conditions of the Where builder
+func (du *DBU) FindCond(o DBObject, w *Where) error {
+	if err := w.Err(); err != nil {
+		return err
+	}
+	if w != nil {
+		for _, column := range w.columns {
+			if err := validColumn(o, column); err != nil {
+				return err
+			}
+		}
+	}
+	query := fmt.Sprintf("select %s from %s", du.dialect.selectFields(o), du.dialect.Quote(tableName(o)))
+	clause, args := w.DialectClause(du.dialect, 1)
+	if len(clause) > 0 {
+		query += " where " + clause
+	}
+	return du.load(o.MemberPointers(), query, args...)
+}
+
+// sliceArg returns the elements of v if it is a slice, other than []byte
+func sliceArg(v interface{}) ([]interface{}, bool) {
+	if _, ok := v.([]byte); ok || v == nil {
+		return nil, false
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return nil, false
+	}
+	list := make([]interface{}, rv.Len())
+	for i := range list {
+		list[i] = rv.Index(i).Interface()
+	}
+	return list, true
+}
+
+// expandArgs replaces each placeholder bound to a slice with a placeholder
+// per element, flattening the slice into the returned args,
+// e.g., "id in (?)" with []int64{1,2,3} becomes "id in (?,?,?)".
+// An empty slice is bound as NULL, matching nothing.
+// Postgres placeholders are renumbered, e.g., "id in ($1) and kind=$2" with
+// []int64{1,2,3} and 4 becomes "id in ($1,$2,$3) and kind=$4"
+func expandArgs(d Dialect, query string, args []interface{}) (string, []interface{}) {
+	expand := false
+	for _, arg := range args {
+		if _, ok := sliceArg(arg); ok {
+			expand = true
+			break
+		}
+	}
+	if !expand {
+		return query, args
+	}
+	if d == Postgres {
+		return expandNumbered(d, query, args)
+	}
+	var b strings.Builder
+	flat := make([]interface{}, 0, len(args))
+	n := 0
+	var quote rune
+	for _, c := range query {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?' && n < len(args):
+			arg := args[n]
+			n++
+			if list, ok := sliceArg(arg); ok {
+				if len(list) == 0 {
+					b.WriteString("NULL")
+					continue
+				}
+				b.WriteString(d.Placeholders(1, len(list)))
+				flat = append(flat, list...)
+				continue
+			}
+			flat = append(flat, arg)
+		}
+		b.WriteRune(c)
+	}
+	return b.String(), append(flat, args[n:]...)
+}
+
+// expandNumbered expands the slice args of a query with numbered placeholders,
+// binding the args in placeholder order, so a reused placeholder is bound again
+func expandNumbered(d Dialect, query string, args []interface{}) (string, []interface{}) {
+	var b strings.Builder
+	flat := make([]interface{}, 0, len(args))
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			end := quotedEnd(query, i)
+			b.WriteString(query[i : end+1])
+			i = end
+			continue
+		case c == '$':
+			end := i + 1
+			for end < len(query) && query[end] >= '0' && query[end] <= '9' {
+				end++
+			}
+			n, err := strconv.Atoi(query[i+1 : end])
+			if err != nil || n < 1 || n > len(args) {
+				break
+			}
+			i = end - 1
+			if list, ok := sliceArg(args[n-1]); ok {
+				if len(list) == 0 {
+					b.WriteString("NULL")
+					continue
+				}
+				b.WriteString(d.Placeholders(len(flat)+1, len(list)))
+				flat = append(flat, list...)
+				continue
+			}
+			flat = append(flat, args[n-1])
+			b.WriteString(d.Placeholder(len(flat)))
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), flat
+}
@@ -0,0 +1,130 @@
+package dbobj
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestWhereClause(t *testing.T) {
+	w := NewWhere().Eq("kind", 2).In("id", 1, 2, 3).Like("name", "a%")
+	clause, args := w.Clause()
+	const want = "kind=? and id in (?,?,?) and name like ?"
+	if clause != want {
+		t.Errorf("expected clause %q, got %q", want, clause)
+	}
+	if !reflect.DeepEqual(args, []interface{}{2, 1, 2, 3, "a%"}) {
+		t.Errorf("unexpected args: %v", args)
+	}
+}
+
+func TestWhereEmpty(t *testing.T) {
+	clause, args := NewWhere().Clause()
+	if clause != "" || len(args) != 0 {
+		t.Errorf("expected empty clause, got %q %v", clause, args)
+	}
+	db := structDBU(t)
+	s := testStruct{}
+	if err := db.FindCond(&s, NewWhere()); err != nil {
+		t.Fatal(err)
+	}
+	if s.ID == 0 {
+		t.Error("expected a record to be loaded")
+	}
+}
+
+func TestWhereInvalid(t *testing.T) {
+	w := NewWhere().Eq("1=1; drop table structs; --", 1)
+	if errors.Cause(w.Err()) != ErrInvalidColumn {
+		t.Errorf("expected ErrInvalidColumn, got %v", w.Err())
+	}
+	db := structDBU(t)
+	s := testStruct{}
+	if err := db.FindCond(&s, NewWhere().Eq("bogus", 1)); errors.Cause(err) != ErrUnknownColumn {
+		t.Errorf("expected ErrUnknownColumn, got %v", err)
+	}
+	check := func(column string) error {
+		return validColumn(&s, column)
+	}
+	if err := NewWhere().Check(check).Eq("bogus", 1).Err(); errors.Cause(err) != ErrUnknownColumn {
+		t.Errorf("expected check hook to reject column, got %v", err)
+	}
+}
+
+func TestFindCond(t *testing.T) {
+	db := structDBU(t)
+	s := testStruct{}
+	w := NewWhere().Eq("kind", 2).In("id", 4, 5).Like("name", "m%")
+	if err := db.FindCond(&s, w); err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "mno" {
+		t.Errorf("expected mno, got %q", s.Name)
+	}
+	u := testStruct{}
+	if err := db.FindCond(&u, NewWhere().In("id")); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound for empty in, got %v", err)
+	}
+}
+
+func TestExpandArgs(t *testing.T) {
+	query, args := expandArgs(SQLite, "name=? and id in (?) and x='?'", []interface{}{"a", []int64{1, 3, 5}})
+	const want = "name=? and id in (?,?,?) and x='?'"
+	if query != want {
+		t.Errorf("expected query %q, got %q", want, query)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"a", int64(1), int64(3), int64(5)}) {
+		t.Errorf("unexpected args: %v", args)
+	}
+	query, args = expandArgs(SQLite, "id in (?)", []interface{}{[]int{}})
+	if query != "id in (NULL)" || len(args) != 0 {
+		t.Errorf("expected empty slice to match nothing, got %q %v", query, args)
+	}
+	query, args = expandArgs(SQLite, "data=?", []interface{}{[]byte("abc")})
+	if query != "data=?" || len(args) != 1 {
+		t.Errorf("expected []byte to be left alone, got %q %v", query, args)
+	}
+}
+
+func TestSliceArgs(t *testing.T) {
+	db := structDBU(t)
+	ids := []int64{1, 3, 5}
+	count, err := db.Count(&testStruct{}, "id in (?)", ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 matching rows, got %d", count)
+	}
+	it, err := db.Iterate(&testStruct{}, "id in (?) order by id", ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+	var names []string
+	for it.Next() {
+		s := testStruct{}
+		if err := it.Scan(&s); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, s.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"abc", "ghi", "mno"}) {
+		t.Errorf("unexpected rows: %v", names)
+	}
+	s := testStruct{}
+	if err := db.FindWhere(&s, "id in (?) and kind=?", ids, 42); err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "ghi" {
+		t.Errorf("expected ghi, got %+v", s)
+	}
+	u := testStruct{}
+	if err := db.FindCond(&u, NewWhere().In("id", ids).Eq("kind", 2)); err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != "mno" {
+		t.Errorf("expected mno, got %+v", u)
+	}
+}